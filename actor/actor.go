@@ -0,0 +1,135 @@
+// Package actor shares a value between goroutines without any mutex.
+//
+// Instead of letting every goroutine touch the value under a lock, one
+// goroutine owns it and is the only one that ever reads or writes it.
+// Everyone else sends it messages asking for a read or a write, and it
+// handles them one at a time, so two accesses can never overlap. This is
+// Go's "don't communicate by sharing memory; share memory by
+// communicating", and what the channel strategies do for a single value.
+package actor
+
+// Value holds a value of type T in an owner goroutine. It has the same
+// methods as safe.SafeValue, so either can be used wherever the other is.
+//
+// The zero value is not usable; create one with NewValue.
+type Value[T any] struct {
+	reqs chan request[T]
+	quit chan struct{}
+	// Closed by the owner goroutine as it exits
+	done chan struct{}
+
+	// Only the owner goroutine touches `val` while it is running; once
+	// `done` is closed nobody writes it any more, so anyone may read it
+	val T
+}
+
+type opKind int
+
+const (
+	opGet opKind = iota
+	opSet
+	opSwap
+	opUpdate
+)
+
+// request is one message to the owner. It carries its own reply channel,
+// so the answer goes back to whoever asked.
+type request[T any] struct {
+	op    opKind
+	val   T
+	fn    func(T) T
+	reply chan T
+}
+
+// NewValue starts the owner goroutine for a Value holding initial. Call
+// Stop when the Value is no longer needed, or the goroutine leaks.
+func NewValue[T any](initial T) *Value[T] {
+	v := &Value[T]{
+		reqs: make(chan request[T]),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+		val:  initial,
+	}
+	go v.own()
+	return v
+}
+
+func (v *Value[T]) own() {
+	defer close(v.done)
+	for {
+		select {
+		case r := <-v.reqs:
+			// The reply channels are buffered, so sending never waits
+			// for the caller and one slow caller can't hold up the rest
+			switch r.op {
+			case opGet:
+				r.reply <- v.val
+			case opSet:
+				v.val = r.val
+				r.reply <- v.val
+			case opSwap:
+				old := v.val
+				v.val = r.val
+				r.reply <- old
+			case opUpdate:
+				v.val = r.fn(v.val)
+				r.reply <- v.val
+			}
+		case <-v.quit:
+			return
+		}
+	}
+}
+
+// do sends r to the owner and waits for the answer. Once the Value is
+// stopped, there is no one left to answer, so it reads the final value
+// instead and reports that r was not handled.
+func (v *Value[T]) do(r request[T]) (T, bool) {
+	r.reply = make(chan T, 1)
+	select {
+	case v.reqs <- r:
+		return <-r.reply, true
+	case <-v.done:
+		return v.val, false
+	}
+}
+
+// Get returns the value. After Stop it returns the value as it was then.
+func (v *Value[T]) Get() T {
+	val, _ := v.do(request[T]{op: opGet})
+	return val
+}
+
+// Set stores val. After Stop it does nothing.
+func (v *Value[T]) Set(val T) {
+	v.do(request[T]{op: opSet, val: val})
+}
+
+// Swap stores val and returns the value it replaced. After Stop it only
+// returns the current value.
+func (v *Value[T]) Swap(val T) T {
+	old, _ := v.do(request[T]{op: opSwap, val: val})
+	return old
+}
+
+// Update replaces the value with fn(current value) and returns the
+// result. fn runs on the owner goroutine, so, as with safe.SafeValue, it
+// must be quick and must not call back into v. After Stop, fn is not
+// called and the current value is returned.
+func (v *Value[T]) Update(fn func(T) T) T {
+	val, _ := v.do(request[T]{op: opUpdate, fn: fn})
+	return val
+}
+
+// Stop shuts the owner goroutine down, after it has answered any request
+// it is in the middle of. It returns once the goroutine has exited, and
+// calling it again does nothing.
+func (v *Value[T]) Stop() {
+	// Only the first Stop gets its message through; later ones find
+	// `done` already closed
+	select {
+	case v.quit <- struct{}{}:
+	case <-v.done:
+	}
+	<-v.done
+}
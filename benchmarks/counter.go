@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abyanjksatu/race-condition/actor"
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+// counter is the one operation being measured: add one and read back the
+// new value, except for the sharded counter, which has no cheap way to
+// read it back and only adds
+type counter interface {
+	inc() int64
+	close()
+}
+
+type mutexCounter struct{ v safe.SafeValue[int64] }
+
+func (c *mutexCounter) inc() int64 {
+	return c.v.Update(func(n int64) int64 { return n + 1 })
+}
+func (c *mutexCounter) close() {}
+
+type atomicCounter struct{ v safe.AtomicNumber }
+
+func (c *atomicCounter) inc() int64 { return c.v.Add(1) }
+func (c *atomicCounter) close()     {}
+
+// channelCounter keeps the count in a server goroutine that owns it, and
+// every increment is a round trip through a channel
+type channelCounter struct {
+	rc     *safe.RequestChannel[int64, int64]
+	cancel context.CancelFunc
+}
+
+func newChannelCounter() *channelCounter {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &channelCounter{rc: safe.NewRequestChannel[int64, int64](), cancel: cancel}
+	var n int64
+	go c.rc.Serve(ctx, func(delta int64) int64 {
+		n += delta
+		return n
+	})
+	return c
+}
+
+func (c *channelCounter) inc() int64 {
+	n, _ := c.rc.Do(context.Background(), 1)
+	return n
+}
+func (c *channelCounter) close() { c.cancel() }
+
+// actorCounter is channelCounter with the owner goroutine behind the
+// same Get/Set/Update methods as safe.SafeValue
+type actorCounter struct{ v *actor.Value[int64] }
+
+func (c *actorCounter) inc() int64 {
+	return c.v.Update(func(n int64) int64 { return n + 1 })
+}
+func (c *actorCounter) close() { c.v.Stop() }
+
+// shardedCounter spreads increments over cache lines, so concurrent ones
+// don't all contend on the same one the way they do with atomicCounter
+type shardedCounter struct{ v *safe.ShardedCounter }
+
+func (c *shardedCounter) inc() int64 {
+	c.v.Inc()
+	return 0
+}
+func (c *shardedCounter) close() {}
+
+var counters = []func() counter{
+	func() counter { return &mutexCounter{} },
+	func() counter { return &atomicCounter{} },
+	func() counter { return &shardedCounter{v: safe.NewShardedCounter(0)} },
+	func() counter { return newChannelCounter() },
+	func() counter { return &actorCounter{v: actor.NewValue[int64](0)} },
+}
+
+var counterTable = table{
+	unit:    "ns/inc",
+	columns: []string{"mutex", "atomic", "sharded", "channel", "actor"},
+	bench: func(column, goroutines int) func(b *testing.B) {
+		return func(b *testing.B) {
+			c := counters[column]()
+			defer c.close()
+			spread(b, goroutines, func(_, _ int) { c.inc() })
+		}
+	},
+}
@@ -0,0 +1,85 @@
+// Command benchmarks compares ways of sharing state between goroutines,
+// for a range of goroutine counts:
+//
+//   - one counter behind a mutex (safe.SafeValue), atomics
+//     (safe.AtomicNumber), atomics split over shards
+//     (safe.ShardedCounter), channels (safe.RequestChannel) and an owner
+//     goroutine (actor.Value)
+//   - a read-heavy number behind a mutex, a read/write mutex
+//     (safe.SafeNumberRW) and an owner goroutine
+//   - a map behind one mutex, sync.Map and a sharded safe.SafeMap
+//   - random numbers from math/rand's global functions, from
+//     safe.ConcurrentRand and from one *rand.Rand behind a mutex
+//
+// Run it from the repository root with
+//
+//	go run ./benchmarks
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"text/tabwriter"
+)
+
+var goroutineCounts = []int{1, 4, 16, 64}
+
+// table is one comparison: a row per goroutine count and a column per
+// implementation, each cell timed by bench
+type table struct {
+	unit    string
+	columns []string
+	bench   func(column, goroutines int) func(b *testing.B)
+}
+
+func main() {
+	testing.Init()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for i, t := range []table{counterTable, readTable, mapTable, randTable} {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprint(w, "goroutines\t")
+		for _, c := range t.columns {
+			fmt.Fprintf(w, "%s %s\t", c, t.unit)
+		}
+		fmt.Fprintln(w)
+		for _, g := range goroutineCounts {
+			fmt.Fprintf(w, "%d\t", g)
+			for c := range t.columns {
+				r := testing.Benchmark(t.bench(c, g))
+				fmt.Fprintf(w, "%d\t", r.NsPerOp())
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	w.Flush()
+}
+
+// spread runs b.N calls of op over the given number of goroutines, and
+// waits for them all. op is passed the goroutine's number and the
+// iteration.
+func spread(b *testing.B, goroutines int, op func(g, i int)) {
+	var wg sync.WaitGroup
+	per := b.N / goroutines
+	b.ResetTimer()
+	for g := 0; g < goroutines; g++ {
+		n := per
+		// The first goroutine picks up the remainder, so exactly b.N
+		// operations are timed
+		if g == 0 {
+			n += b.N % goroutines
+		}
+		wg.Add(1)
+		go func(g, n int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				op(g, i)
+			}
+		}(g, n)
+	}
+	wg.Wait()
+	b.StopTimer()
+}
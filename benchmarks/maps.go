@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+// mapKeys is how many distinct keys the map benchmark spreads its
+// operations over
+const mapKeys = 1024
+
+type intMap interface {
+	load(k int) (int, bool)
+	store(k, v int)
+}
+
+type mutexMap struct {
+	m    sync.Mutex
+	vals map[int]int
+}
+
+func (m *mutexMap) load(k int) (int, bool) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	v, ok := m.vals[k]
+	return v, ok
+}
+
+func (m *mutexMap) store(k, v int) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.vals[k] = v
+}
+
+type syncMap struct{ m sync.Map }
+
+func (m *syncMap) load(k int) (int, bool) {
+	v, ok := m.m.Load(k)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+func (m *syncMap) store(k, v int) { m.m.Store(k, v) }
+
+type shardedMap struct{ m *safe.SafeMap[int, int] }
+
+func (m shardedMap) load(k int) (int, bool) { return m.m.Get(k) }
+func (m shardedMap) store(k, v int)         { m.m.Set(k, v) }
+
+var intMaps = []func() intMap{
+	func() intMap { return &mutexMap{vals: make(map[int]int)} },
+	func() intMap { return &syncMap{} },
+	func() intMap { return shardedMap{safe.NewSafeMap[int, int](0)} },
+}
+
+// mapTable times a mix of nine reads to every write, with each goroutine
+// walking the keys from a different starting point
+var mapTable = table{
+	unit:    "ns/op",
+	columns: []string{"mutex map", "sync.Map", "SafeMap"},
+	bench: func(column, goroutines int) func(b *testing.B) {
+		return func(b *testing.B) {
+			m := intMaps[column]()
+			for k := 0; k < mapKeys; k++ {
+				m.store(k, k)
+			}
+			spread(b, goroutines, func(g, i int) {
+				k := (g*7919 + i) % mapKeys
+				if i%10 == 0 {
+					m.store(k, i)
+				} else {
+					m.load(k)
+				}
+			})
+		}
+	},
+}
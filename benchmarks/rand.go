@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+// lockedRand is the usual way to share one *rand.Rand, which is not safe
+// for concurrent use by itself
+type lockedRand struct {
+	m sync.Mutex
+	r *rand.Rand
+}
+
+func (l *lockedRand) Intn(n int) int {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.r.Intn(n)
+}
+
+var randTable = table{
+	unit:    "ns/intn",
+	columns: []string{"global", "ConcurrentRand", "locked"},
+	bench: func(column, goroutines int) func(b *testing.B) {
+		return func(b *testing.B) {
+			var intn func(n int) int
+			switch column {
+			case 0:
+				intn = rand.Intn
+			case 1:
+				intn = safe.NewConcurrentRand().Intn
+			default:
+				intn = (&lockedRand{r: rand.New(rand.NewSource(1))}).Intn
+			}
+			spread(b, goroutines, func(_, _ int) { intn(100) })
+		}
+	},
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/abyanjksatu/race-condition/actor"
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+// readMostly is a number that many goroutines read while one keeps
+// writing to it
+type readMostly interface {
+	get() int
+	add(delta int)
+	close()
+}
+
+type mutexNumber struct{ v safe.SafeValue[int] }
+
+func (n *mutexNumber) get() int { return n.v.Get() }
+func (n *mutexNumber) add(delta int) {
+	n.v.Update(func(v int) int { return v + delta })
+}
+func (n *mutexNumber) close() {}
+
+type rwNumber struct{ v safe.SafeNumberRW }
+
+func (n *rwNumber) get() int      { return n.v.Get() }
+func (n *rwNumber) add(delta int) { n.v.Add(delta) }
+func (n *rwNumber) close()        {}
+
+// actorNumber answers every read with a message to its owner, so reads
+// queue behind each other and the writer just as they do with a mutex
+type actorNumber struct{ v *actor.Value[int] }
+
+func (n *actorNumber) get() int { return n.v.Get() }
+func (n *actorNumber) add(delta int) {
+	n.v.Update(func(v int) int { return v + delta })
+}
+func (n *actorNumber) close() { n.v.Stop() }
+
+var readMostlys = []func() readMostly{
+	func() readMostly { return &mutexNumber{} },
+	func() readMostly { return &rwNumber{} },
+	func() readMostly { return &actorNumber{v: actor.NewValue(0)} },
+}
+
+var readTable = table{
+	unit:    "ns/read",
+	columns: []string{"mutex", "rwmutex", "actor"},
+	bench: func(column, goroutines int) func(b *testing.B) {
+		return func(b *testing.B) {
+			n := readMostlys[column]()
+			defer n.close()
+			// One extra goroutine keeps writing for as long as the
+			// readers are running
+			stop := make(chan struct{})
+			writerDone := make(chan struct{})
+			go func() {
+				defer close(writerDone)
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						n.add(1)
+					}
+				}
+			}()
+			spread(b, goroutines, func(_, _ int) { n.get() })
+			close(stop)
+			<-writerDone
+		}
+	},
+}
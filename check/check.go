@@ -0,0 +1,212 @@
+// Package check looks for concurrency bugs that -race can't see, by
+// checking that concurrent operations on a type give answers it could
+// have given if they had run one at a time.
+//
+// The race detector only reports accesses that aren't synchronized at
+// all. A type can lock every access and still be wrong, say by reading
+// and writing under two separate locks so an update slips in between.
+// Such a type is not linearizable: there is no order of its operations,
+// each taking effect at some instant between its call and return, that
+// explains what every goroutine saw.
+//
+// Run drives a type with random operations from several goroutines and
+// records each one's result and when it started and finished. It then
+// searches for such an order of the history against a Model, a simple
+// sequential version of the type. Finding none means the type is broken.
+package check
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Op is one operation in a history: what was asked, what came back, and
+// when it was called and when it returned, by a clock shared by all the
+// goroutines.
+type Op[I, O any] struct {
+	Goroutine    int
+	Input        I
+	Output       O
+	Call, Return int64
+}
+
+func (op Op[I, O]) String() string {
+	return fmt.Sprintf("goroutine %d [%d, %d]: %v -> %v", op.Goroutine, op.Call, op.Return, op.Input, op.Output)
+}
+
+// Model is the sequential version of a type, against which histories are
+// checked. S is its state, I an operation and O that operation's result.
+type Model[S, I, O any] struct {
+	Init func() S
+	// Step runs in against s as if nothing else was running, and reports
+	// whether it would have returned out, and the state after it. It must
+	// not change s itself, since the search goes back to earlier states.
+	Step func(s S, in I, out O) (S, bool)
+}
+
+// Linearizable reports whether history can be explained by running its
+// operations one at a time against m, in some order that respects real
+// time: an operation that returned before another was called must come
+// first.
+//
+// The search tries every order, so it takes time exponential in the
+// length of the history at worst; keep histories to a few dozen
+// operations. States are compared by how fmt prints them.
+func Linearizable[S, I, O any](m Model[S, I, O], history []Op[I, O]) bool {
+	s := &search[S, I, O]{
+		model: m,
+		ops:   history,
+		done:  make([]bool, len(history)),
+		seen:  make(map[string]bool),
+	}
+	return s.from(m.Init(), len(history))
+}
+
+type search[S, I, O any] struct {
+	model Model[S, I, O]
+	ops   []Op[I, O]
+	// `done` marks the operations already placed in the order
+	done []bool
+	// `seen` remembers every pair of placed operations and state that
+	// was already found to lead nowhere, so it isn't searched again
+	seen map[string]bool
+}
+
+// from reports whether the left operations not yet placed can all be
+// placed, starting from state
+func (s *search[S, I, O]) from(state S, left int) bool {
+	if left == 0 {
+		return true
+	}
+	key := s.key(state)
+	if s.seen[key] {
+		return false
+	}
+	// An operation can only go next if it was called before every other
+	// remaining one returned; otherwise one of those finished entirely
+	// before it began and must come first
+	first := int64(math.MaxInt64)
+	for i, op := range s.ops {
+		if !s.done[i] && op.Return < first {
+			first = op.Return
+		}
+	}
+	for i, op := range s.ops {
+		if s.done[i] || op.Call > first {
+			continue
+		}
+		next, ok := s.model.Step(state, op.Input, op.Output)
+		if !ok {
+			continue
+		}
+		s.done[i] = true
+		if s.from(next, left-1) {
+			return true
+		}
+		s.done[i] = false
+	}
+	s.seen[key] = true
+	return false
+}
+
+func (s *search[S, I, O]) key(state S) string {
+	var b strings.Builder
+	for _, d := range s.done {
+		if d {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	fmt.Fprint(&b, state)
+	return b.String()
+}
+
+// Config says how much work Run does.
+type Config struct {
+	// Histories is how many separate histories to record and check, each
+	// on a new instance of the type
+	Histories int
+	// Goroutines is how many goroutines run operations at once, and Ops
+	// how many each of them runs, per history
+	Goroutines int
+	Ops        int
+	// Seed makes the operations chosen repeatable, though not the order
+	// the goroutines run them in
+	Seed uint64
+}
+
+// Error is returned by Run for the first history that isn't linearizable.
+type Error struct {
+	History int
+	Ops     []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("history %d is not linearizable:\n  %s", e.History, strings.Join(e.Ops, "\n  "))
+}
+
+// Run records cfg.Histories histories and checks each against m. For
+// each one it calls newTarget for a fresh instance of the type, as a
+// function that runs one operation, and has every goroutine run
+// operations created by gen.
+func Run[S, I, O any](cfg Config, m Model[S, I, O], newTarget func() func(I) O, gen func(r *rand.Rand) I) error {
+	for h := 0; h < cfg.Histories; h++ {
+		history := record(cfg, h, newTarget(), gen)
+		if !Linearizable(m, history) {
+			e := &Error{History: h}
+			// In the order they were called, which is easier to follow
+			slices.SortFunc(history, func(a, b Op[I, O]) int { return cmp.Compare(a.Call, b.Call) })
+			for _, op := range history {
+				e.Ops = append(e.Ops, op.String())
+			}
+			return e
+		}
+	}
+	return nil
+}
+
+func record[I, O any](cfg Config, h int, target func(I) O, gen func(r *rand.Rand) I) []Op[I, O] {
+	var (
+		// An atomic counter is enough for a clock: if one operation
+		// returned before another was called, it got the smaller number
+		clock atomic.Int64
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		ops   = make([][]Op[I, O], cfg.Goroutines)
+	)
+	for g := 0; g < cfg.Goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(cfg.Seed, uint64(h*cfg.Goroutines+g)))
+			// Choose the operations up front, so the time spent choosing
+			// doesn't spread the real ones apart
+			inputs := make([]I, cfg.Ops)
+			for i := range inputs {
+				inputs[i] = gen(r)
+			}
+			<-start
+			for _, in := range inputs {
+				op := Op[I, O]{Goroutine: g, Input: in}
+				op.Call = clock.Add(1)
+				op.Output = target(in)
+				op.Return = clock.Add(1)
+				ops[g] = append(ops[g], op)
+			}
+		}(g)
+	}
+	close(start)
+	wg.Wait()
+	var history []Op[I, O]
+	for _, o := range ops {
+		history = append(history, o...)
+	}
+	return history
+}
@@ -0,0 +1,218 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+// Suite checks one of the safe types.
+type Suite struct {
+	Name string
+	Run  func(cfg Config) error
+}
+
+// Suites has a Suite for each safe type this package knows how to check.
+var Suites = []Suite{
+	{"SafeValue", checkValue},
+	{"SafeCounter", checkCounter},
+	{"SafeMap", checkMap},
+	{"BoundedQueue", checkQueue},
+}
+
+// The operations below use only a few distinct numbers and keys, so
+// that goroutines keep running into each other's writes.
+
+// call is an operation named op with an argument, such as set(3) or
+// get(1); arg is unused by operations that take none.
+type call struct {
+	op  string
+	arg int
+}
+
+func (c call) String() string {
+	return fmt.Sprintf("%s(%d)", c.op, c.arg)
+}
+
+func pick(r *rand.Rand, ops ...string) call {
+	return call{op: ops[r.IntN(len(ops))], arg: r.IntN(4)}
+}
+
+func checkValue(cfg Config) error {
+	m := Model[int, call, int]{
+		Init: func() int { return 0 },
+		Step: func(s int, in call, out int) (int, bool) {
+			switch in.op {
+			case "get":
+				return s, out == s
+			case "set":
+				return in.arg, true
+			case "swap":
+				return in.arg, out == s
+			default: // add
+				return s + in.arg, out == s+in.arg
+			}
+		},
+	}
+	newTarget := func() func(call) int {
+		var v safe.SafeValue[int]
+		return func(in call) int {
+			switch in.op {
+			case "get":
+				return v.Get()
+			case "set":
+				v.Set(in.arg)
+				return 0
+			case "swap":
+				return v.Swap(in.arg)
+			default:
+				return v.Update(func(n int) int { return n + in.arg })
+			}
+		}
+	}
+	return Run(cfg, m, newTarget, func(r *rand.Rand) call {
+		return pick(r, "get", "set", "swap", "add")
+	})
+}
+
+func checkCounter(cfg Config) error {
+	// Every operation returns the value it left the counter with
+	m := Model[int64, call, int64]{
+		Init: func() int64 { return 0 },
+		Step: func(s int64, in call, out int64) (int64, bool) {
+			switch in.op {
+			case "inc":
+				s++
+			case "dec":
+				s--
+			case "add":
+				s += int64(in.arg)
+			}
+			return s, out == s
+		},
+	}
+	newTarget := func() func(call) int64 {
+		var c safe.SafeCounter
+		return func(in call) int64 {
+			switch in.op {
+			case "inc":
+				return c.Inc()
+			case "dec":
+				return c.Dec()
+			case "add":
+				return c.Add(int64(in.arg))
+			default: // value
+				return c.Value()
+			}
+		}
+	}
+	return Run(cfg, m, newTarget, func(r *rand.Rand) call {
+		return pick(r, "inc", "dec", "add", "value")
+	})
+}
+
+// found is the result of a lookup, or of a queue operation that may have
+// given up
+type found struct {
+	val int
+	ok  bool
+}
+
+func (f found) String() string {
+	if !f.ok {
+		return "-"
+	}
+	return fmt.Sprint(f.val)
+}
+
+func checkMap(cfg Config) error {
+	m := Model[map[int]int, call, found]{
+		Init: func() map[int]int { return map[int]int{} },
+		Step: func(s map[int]int, in call, out found) (map[int]int, bool) {
+			// Keys and values both come from `arg`, so a key's value is
+			// always the key itself, which is enough to tell a stored
+			// value from a missing one
+			switch in.op {
+			case "get":
+				v, ok := s[in.arg]
+				return s, out == found{v, ok}
+			case "set":
+				s = maps.Clone(s)
+				s[in.arg] = in.arg
+				return s, true
+			default: // delete
+				s = maps.Clone(s)
+				delete(s, in.arg)
+				return s, true
+			}
+		},
+	}
+	newTarget := func() func(call) found {
+		// Two shards for four keys, so some keys share a shard and some
+		// don't
+		sm := safe.NewSafeMap[int, int](2)
+		return func(in call) found {
+			switch in.op {
+			case "get":
+				v, ok := sm.Get(in.arg)
+				return found{v, ok}
+			case "set":
+				sm.Set(in.arg, in.arg)
+			default:
+				sm.Delete(in.arg)
+			}
+			return found{}
+		}
+	}
+	return Run(cfg, m, newTarget, func(r *rand.Rand) call {
+		return pick(r, "get", "get", "set", "delete")
+	})
+}
+
+// queueCapacity is small, so the queue is often full as well as empty
+const queueCapacity = 2
+
+// queueWait is how long a queue operation waits before giving up. One
+// that gives up must not have changed the queue.
+const queueWait = time.Millisecond
+
+func checkQueue(cfg Config) error {
+	m := Model[[]int, call, found]{
+		Init: func() []int { return nil },
+		Step: func(s []int, in call, out found) ([]int, bool) {
+			if !out.ok {
+				return s, true
+			}
+			if in.op == "enqueue" {
+				if len(s) == queueCapacity {
+					return s, false
+				}
+				return append(slices.Clip(s), in.arg), true
+			}
+			if len(s) == 0 || s[0] != out.val {
+				return s, false
+			}
+			return s[1:], true
+		},
+	}
+	newTarget := func() func(call) found {
+		q := safe.NewBoundedQueue[int](queueCapacity)
+		return func(in call) found {
+			ctx, cancel := context.WithTimeout(context.Background(), queueWait)
+			defer cancel()
+			if in.op == "enqueue" {
+				return found{in.arg, q.Enqueue(ctx, in.arg) == nil}
+			}
+			v, err := q.Dequeue(ctx)
+			return found{v, err == nil}
+		}
+	}
+	return Run(cfg, m, newTarget, func(r *rand.Rand) call {
+		return pick(r, "enqueue", "dequeue")
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/check"
+)
+
+func init() {
+	commands["check"] = command{
+		usage: "check [-histories N] [-goroutines N] [-ops N] [-seed N]",
+		run:   checkCmd,
+	}
+}
+
+// checkCmd runs random concurrent operations against each safe type and
+// checks that every history could have happened one operation at a time
+func checkCmd(args []string) error {
+	fs := newFlagSet("check")
+	histories := fs.Int("histories", 500, "number of histories to check per type")
+	goroutines := fs.Int("goroutines", 4, "number of goroutines in each history")
+	ops := fs.Int("ops", 6, "number of operations each goroutine runs")
+	seed := fs.Uint64("seed", uint64(time.Now().UnixNano()), "seed for choosing the operations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *histories < 1 || *goroutines < 1 || *ops < 1 {
+		return errors.New("check: -histories, -goroutines and -ops must be at least 1")
+	}
+	// The search is exponential in the length of a history at worst
+	if *goroutines**ops > 64 {
+		return errors.New("check: at most 64 operations per history (-goroutines times -ops)")
+	}
+
+	cfg := check.Config{Histories: *histories, Goroutines: *goroutines, Ops: *ops, Seed: *seed}
+	fmt.Printf("seed %d\n", *seed)
+	failed := false
+	for _, s := range check.Suites {
+		if err := s.Run(cfg); err != nil {
+			fmt.Printf("%s: %v\n", s.Name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %d histories, all linearizable\n", s.Name, *histories)
+	}
+	if failed {
+		return errors.New("check: some types are not linearizable")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is one subcommand of the binary, e.g. `race-condition run`
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+// commands holds every subcommand by name. Each one registers itself
+// from an init func in its own file
+var commands = map[string]command{}
+
+func runCommand(name string, args []string) error {
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return nil
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+	return cmd.run(args)
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "usage: race-condition [command]")
+	fmt.Fprintln(os.Stderr, "\nWith no command, every strategy is demonstrated once. Commands:")
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  race-condition", commands[name].usage)
+	}
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors
+// instead of exiting, so every command fails the same way
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseWithArg parses flags that may come before or after a single
+// positional argument, as in both `run -goroutines 8 mutex` and
+// `run mutex -goroutines 8`, and returns that argument
+func parseWithArg(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() == 0 {
+		return "", nil
+	}
+	arg := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return "", err
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("%s: unexpected arguments %q", fs.Name(), fs.Args())
+	}
+	return arg, nil
+}
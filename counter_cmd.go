@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/abyanjksatu/race-condition/racy"
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+func init() {
+	commands["counter"] = command{
+		usage: "counter [-goroutines N] [-increments N]",
+		run:   counterCmd,
+	}
+}
+
+// counterCmd increments a safe and a racy counter from many goroutines
+// and compares the totals with the number of increments made
+func counterCmd(args []string) error {
+	fs := newFlagSet("counter")
+	goroutines := fs.Int("goroutines", 8, "number of goroutines incrementing at once")
+	increments := fs.Int("increments", 100000, "number of increments per goroutine")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *goroutines < 1 || *increments < 1 {
+		return errors.New("counter: -goroutines and -increments must be at least 1")
+	}
+	want := int64(*goroutines) * int64(*increments)
+
+	var c safe.SafeCounter
+	hammer(*goroutines, *increments, func() { c.Inc() })
+	fmt.Printf("SafeCounter:  %d of %d, %d lost\n", c.Value(), want, want-c.Value())
+
+	// Run this under `go run -race` to have the race detector point at
+	// the problem instead of just counting the damage
+	var r racy.Counter
+	hammer(*goroutines, *increments, func() { r.Inc() })
+	fmt.Printf("racy.Counter: %d of %d, %d lost\n", r.Value(), want, want-r.Value())
+	if r.Value() == want {
+		// Goroutines only collide if they really run at the same time, which
+		// takes more than one CPU
+		fmt.Println("(no updates lost this time; try more -increments, or a machine with more CPUs)")
+	}
+
+	if c.Value() != want {
+		return errors.New("counter: SafeCounter lost updates")
+	}
+	return nil
+}
+
+// hammer calls inc n times from each of goroutines goroutines at once
+func hammer(goroutines, n int, inc func()) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i := 0; i < n; i++ {
+				inc()
+			}
+		}()
+	}
+	// Release everyone together, to have them collide as much as possible
+	close(start)
+	wg.Wait()
+}
@@ -0,0 +1,124 @@
+// Package diagnose runs a function under a watchdog and reports whether
+// it finished in time and whether it left goroutines behind.
+//
+// Deadlocks and goroutine leaks are silent: a stuck program just sits
+// there, and a leaked goroutine quietly holds on to its memory forever.
+// Run gives both a clear symptom: a stall produces a dump of every
+// goroutine's stack, taken while it is stuck, and a leak shows up as a
+// goroutine count that doesn't come back down.
+package diagnose
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Options configures Run. Zero values pick the defaults.
+type Options struct {
+	// Timeout is how long fn may run before it counts as stalled.
+	// The default is 5s.
+	Timeout time.Duration
+	// Settle is how long to wait, after fn returns, for the goroutines
+	// it started to exit. The default is 100ms.
+	Settle time.Duration
+}
+
+// Report is what Run found out about one function.
+type Report struct {
+	Name    string
+	Elapsed time.Duration
+	// Stalled is true if fn hadn't returned within the timeout
+	Stalled bool
+	// GoroutinesBefore and GoroutinesAfter are the process's goroutine
+	// counts when fn was started and once it had settled
+	GoroutinesBefore int
+	GoroutinesAfter  int
+	// Stacks holds every goroutine's stack trace, taken at the moment fn
+	// stalled or, for a leak, once it had finished settling
+	Stacks []byte
+}
+
+// Leaked returns how many more goroutines there were after fn than
+// before it.
+func (r Report) Leaked() int {
+	if n := r.GoroutinesAfter - r.GoroutinesBefore; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// Err returns an error describing a stall or a leak, or nil if fn
+// finished in time and cleaned up after itself.
+func (r Report) Err() error {
+	switch {
+	case r.Stalled:
+		return fmt.Errorf("%s: still running after %v", r.Name, r.Elapsed.Round(time.Millisecond))
+	case r.Leaked() > 0:
+		return fmt.Errorf("%s: leaked %d goroutine(s)", r.Name, r.Leaked())
+	}
+	return nil
+}
+
+// Run calls fn and watches it. Goroutines started by anything else while
+// fn runs also count towards the leak check, so don't run other work
+// alongside it.
+//
+// If fn stalls, Run returns without waiting for it; Go can't stop fn, so
+// it stays blocked in the background.
+func Run(name string, fn func(), opts Options) Report {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = 100 * time.Millisecond
+	}
+	r := Report{Name: name, GoroutinesBefore: runtime.NumGoroutine()}
+
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	timer := time.NewTimer(opts.Timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		r.Elapsed = time.Since(start)
+	case <-timer.C:
+		r.Elapsed = time.Since(start)
+		r.Stalled = true
+		r.Stacks = allStacks()
+		r.GoroutinesAfter = runtime.NumGoroutine()
+		return r
+	}
+
+	// Goroutines fn started may need a moment to see that they are done
+	// and exit, so give them until `Settle` before calling it a leak
+	deadline := time.Now().Add(opts.Settle)
+	for {
+		r.GoroutinesAfter = runtime.NumGoroutine()
+		if r.GoroutinesAfter <= r.GoroutinesBefore || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if r.Leaked() > 0 {
+		r.Stacks = allStacks()
+	}
+	return r
+}
+
+// allStacks returns the stack traces of every goroutine, growing the
+// buffer until they fit
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
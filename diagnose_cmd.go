@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/abyanjksatu/race-condition/diagnose"
+)
+
+func init() {
+	commands["diagnose"] = command{
+		usage: "diagnose [-timeout D] [-settle D] [-racy] [-stacks]",
+		run:   diagnoseCmd,
+	}
+}
+
+// diagnoseCmd runs every strategy under the watchdog and fails if any of
+// them stalls or leaks goroutines
+func diagnoseCmd(args []string) error {
+	fs := newFlagSet("diagnose")
+	var opts diagnose.Options
+	fs.DurationVar(&opts.Timeout, "timeout", 0, "how long a strategy may run before it counts as stalled (default 5s)")
+	fs.DurationVar(&opts.Settle, "settle", 0, "how long to wait for a strategy's goroutines to exit (default 100ms)")
+	broken := fs.Bool("racy", false, "check the broken, unsynchronized versions instead")
+	stacks := fs.Bool("stacks", false, "print goroutine stacks for any strategy that fails")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, s := range strategies {
+		get := func() int { return s.get(nil) }
+		if *broken {
+			get = s.racy
+		}
+		r := diagnose.Run(s.name, func() { get() }, opts)
+		if err := r.Err(); err != nil {
+			failed++
+			fmt.Println("FAIL", err)
+			if *stacks {
+				os.Stdout.Write(r.Stacks)
+			}
+			continue
+		}
+		fmt.Printf("ok   %s: finished in %v, goroutines %d -> %d\n", s.name, r.Elapsed, r.GoroutinesBefore, r.GoroutinesAfter)
+	}
+	if failed > 0 {
+		return fmt.Errorf("diagnose: %d of %d strategies failed", failed, len(strategies))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/group"
+)
+
+func init() {
+	commands["fanout"] = command{
+		usage: "fanout [-tasks N] [-fail N]",
+		run:   fanoutCmd,
+	}
+}
+
+type squared struct {
+	n, square int
+}
+
+// fanoutCmd squares the numbers up to -tasks in a goroutine each and
+// gathers the answers, stopping everything early if task -fail fails
+func fanoutCmd(args []string) error {
+	fs := newFlagSet("fanout")
+	tasks := fs.Int("tasks", 10, "number of tasks to fan out")
+	fail := fs.Int("fail", -1, "number of the task that fails, or -1 for none")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tasks < 1 {
+		return errors.New("fanout: -tasks must be at least 1")
+	}
+
+	g, ctx := group.WithContext(context.Background())
+	// Tasks don't touch the result slice: they only send on this channel,
+	// and a single goroutine below does all the appending. With no second
+	// goroutine using the slice, there's nothing to race with
+	results := make(chan squared)
+	for n := 0; n < *tasks; n++ {
+		g.Go(func() error {
+			// Pretend each task does some work that takes a while
+			select {
+			case <-time.After(time.Duration(rand.IntN(50)) * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if n == *fail {
+				return fmt.Errorf("task %d failed", n)
+			}
+			// Sending has to watch ctx too: once the collector has stopped
+			// listening, nobody would ever receive this
+			select {
+			case results <- squared{n, n * n}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+
+	var got []squared
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for r := range results {
+			got = append(got, r)
+		}
+	}()
+	err := g.Wait()
+	// Every sender has returned, so it's safe to close, and once the
+	// collector has finished `got` is ours alone
+	close(results)
+	<-collected
+
+	// Results arrive in whatever order the tasks finished
+	sort.Slice(got, func(i, j int) bool { return got[i].n < got[j].n })
+	for _, r := range got {
+		fmt.Printf("%d² = %d\n", r.n, r.square)
+	}
+	fmt.Printf("%d of %d tasks delivered a result\n", len(got), *tasks)
+	return err
+}
@@ -0,0 +1,61 @@
+// Package group runs related tasks in goroutines and reports the first
+// error any of them returns, in the style of golang.org/x/sync/errgroup.
+//
+// A goroutine has no return value, so an error from one can only get out
+// through shared state or a channel. A Group keeps the first one, and,
+// when made with WithContext, cancels a context the remaining tasks can
+// watch, so they stop early instead of finishing work nobody will use.
+package group
+
+import (
+	"context"
+	"sync"
+)
+
+// Group waits for a set of tasks and keeps the first error among them.
+// The zero value is ready to use and doesn't cancel anything on error.
+type Group struct {
+	wg sync.WaitGroup
+
+	// Set by WithContext; called with the first error, and again by Wait
+	cancel context.CancelCauseFunc
+
+	// `once` makes sure only the first error is kept, however many tasks
+	// fail at the same time
+	once sync.Once
+	err  error
+}
+
+// WithContext returns a Group and a context derived from ctx that is
+// cancelled when a task first returns an error, or when Wait returns,
+// whichever happens first.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go runs fn in a new goroutine.
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				if g.cancel != nil {
+					g.cancel(err)
+				}
+			})
+		}
+	}()
+}
+
+// Wait blocks until every task started with Go has returned, and returns
+// the first non-nil error, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(g.err)
+	}
+	return g.err
+}
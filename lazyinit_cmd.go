@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/abyanjksatu/race-condition/racy"
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+func init() {
+	commands["lazyinit"] = command{
+		usage: "lazyinit [-rounds N] [-readers N]",
+		run:   lazyinitCmd,
+	}
+}
+
+// lazyConfig stands in for something expensive to build, such as
+// settings read from a file. A complete one has every field set.
+type lazyConfig struct {
+	host string
+	port int
+	tls  bool
+}
+
+func (c *lazyConfig) complete() bool {
+	return c.host != "" && c.port != 0 && c.tls
+}
+
+func loadConfig(c *lazyConfig) {
+	// Yield between fields, as a real load would while waiting on disk,
+	// so readers get a chance to run part way through
+	c.host = "localhost"
+	runtime.Gosched()
+	c.port = 8080
+	runtime.Gosched()
+	c.tls = true
+}
+
+// lazyinitCmd has readers race to get a lazily built config, many times
+// over, and counts the rounds in which one of them got an incomplete one
+func lazyinitCmd(args []string) error {
+	fs := newFlagSet("lazyinit")
+	rounds := fs.Int("rounds", 1000, "number of times to build the config")
+	readers := fs.Int("readers", 8, "number of goroutines asking for it at once")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *rounds < 1 || *readers < 1 {
+		return errors.New("lazyinit: -rounds and -readers must be at least 1")
+	}
+
+	versions := []struct {
+		name string
+		racy bool
+		get  func() func() *lazyConfig
+	}{
+		{"racy.PublishFirstPointer", true, func() func() *lazyConfig {
+			return racy.NewPublishFirstPointer(loadConfig).Get
+		}},
+		{"racy.LazyPointer (classic DCL)", true, func() func() *lazyConfig {
+			return racy.NewLazyPointer(loadConfig).Get
+		}},
+		{"safe.Lazy (sync.Once)", false, func() func() *lazyConfig {
+			return safe.NewLazy(func() *lazyConfig {
+				c := &lazyConfig{}
+				loadConfig(c)
+				return c
+			}).Get
+		}},
+		{"safe.LazyPointer (atomic.Pointer)", false, func() func() *lazyConfig {
+			return safe.NewLazyPointer(loadConfig).Get
+		}},
+	}
+	// Run this under `go run -race` to have the race detector report the
+	// broken version, even in rounds where nothing visibly went wrong
+	var safeBroken bool
+	for _, v := range versions {
+		var bad atomic.Int64
+		for r := 0; r < *rounds; r++ {
+			get := v.get()
+			var saw atomic.Bool
+			hammer(*readers, 1, func() {
+				if !get().complete() {
+					saw.Store(true)
+				}
+			})
+			if saw.Load() {
+				bad.Add(1)
+			}
+		}
+		fmt.Printf("%-35s %d of %d rounds saw a half-built config\n", v.name, bad.Load(), *rounds)
+		if !v.racy && bad.Load() > 0 {
+			safeBroken = true
+		}
+	}
+	// A count of 0 for the classic version doesn't make it correct; it
+	// means this CPU and compiler happened not to reorder the writes
+	fmt.Println("racy.LazyPointer may well show 0 here; only go run -race reliably catches it")
+	if safeBroken {
+		return errors.New("lazyinit: a safe version handed out a half-built config")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package limit
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimiter lets events through at a steady rate, with room for short
+// bursts: a token bucket.
+//
+// The bucket is a buffered channel, and each value in it is a token. A
+// goroutine adds one token every interval, unless the bucket is full, and
+// every event takes one out, waiting for the next if it's empty.
+type RateLimiter struct {
+	tokens chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewRateLimiter returns a RateLimiter that allows one event per every,
+// and bursts of up to burst events. It starts with a full bucket. Call
+// Stop when it is no longer needed. It panics if every is not positive.
+func NewRateLimiter(every time.Duration, burst int) *RateLimiter {
+	// Checked here, since the ticker that would otherwise panic over it
+	// runs on a goroutine of our own, where the panic can't be recovered
+	if every <= 0 {
+		panic("limit: non-positive interval for NewRateLimiter")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l := &RateLimiter{
+		tokens: make(chan struct{}, burst),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < burst; i++ {
+		l.tokens <- struct{}{}
+	}
+	go l.refill(every)
+	return l
+}
+
+func (l *RateLimiter) refill(every time.Duration) {
+	defer close(l.done)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			select {
+			case l.tokens <- struct{}{}:
+			default:
+				// The bucket is full; this token is simply not added
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Wait blocks until an event is allowed, or returns ctx.Err() if ctx is
+// done first.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	select {
+	case <-l.tokens:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Allow reports whether an event is allowed right now, using up a token
+// if it is.
+func (l *RateLimiter) Allow() bool {
+	select {
+	case <-l.tokens:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops adding tokens, and must only be called once. Waits still
+// get the tokens left in the bucket, and then wait for their context.
+func (l *RateLimiter) Stop() {
+	close(l.stop)
+	<-l.done
+}
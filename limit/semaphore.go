@@ -0,0 +1,103 @@
+// Package limit caps how much work runs at once, and how often, using
+// nothing but channels.
+//
+// A done channel passes a single signal from one goroutine to another.
+// A buffered channel can hold several values, so its capacity can stand
+// for a number of something: free slots in Semaphore, and tokens that
+// refill over time in RateLimiter.
+package limit
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTooHeavy is returned by Semaphore.Acquire for a weight bigger than
+// the semaphore's capacity, which could never be granted.
+var ErrTooHeavy = errors.New("limit: weight exceeds semaphore capacity")
+
+// Semaphore lets holders share a fixed capacity, each taking as much of
+// it as it needs.
+//
+// Every unit of capacity in use is a value sitting in the `held` channel,
+// so acquiring is sending and releasing is receiving, and a full channel
+// makes senders wait. A heavy acquire sends several values one at a time,
+// and if two did that at once, each could get half of what it needs and
+// wait forever for the rest. So acquirers first take the `turn`, a
+// channel with room for one, and only the one holding it sends.
+type Semaphore struct {
+	held chan struct{}
+	turn chan struct{}
+}
+
+// NewSemaphore returns a Semaphore with the given capacity.
+func NewSemaphore(capacity int) *Semaphore {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Semaphore{
+		held: make(chan struct{}, capacity),
+		turn: make(chan struct{}, 1),
+	}
+}
+
+// Acquire takes weight units of capacity, waiting until they are free.
+// Waiters are served in turn, so a heavy one isn't passed over by light
+// ones. It returns ctx.Err(), holding nothing, if ctx is done first.
+func (s *Semaphore) Acquire(ctx context.Context, weight int) error {
+	if weight > cap(s.held) {
+		return ErrTooHeavy
+	}
+	select {
+	case s.turn <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.turn }()
+	for i := 0; i < weight; i++ {
+		select {
+		case s.held <- struct{}{}:
+		case <-ctx.Done():
+			// Hand back what we got so far, or it would be lost for good
+			s.Release(i)
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// TryAcquire takes weight units of capacity if it can without waiting,
+// and reports whether it did. It fails while any Acquire is waiting, even
+// if there would be room for weight: that Acquire holds the turn, and
+// jumping ahead of it is the unfairness taking turns is there to stop.
+func (s *Semaphore) TryAcquire(weight int) bool {
+	// A select with a default doesn't wait: it takes the default case if
+	// no other case is ready
+	select {
+	case s.turn <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-s.turn }()
+	if cap(s.held)-len(s.held) < weight {
+		return false
+	}
+	// Only the holder of `turn` sends, and nobody else can fill up the
+	// room we just saw, so these sends can't block
+	for i := 0; i < weight; i++ {
+		s.held <- struct{}{}
+	}
+	return true
+}
+
+// Release gives back weight units of capacity. Releasing more than is
+// held is a bug in the caller, and panics.
+func (s *Semaphore) Release(weight int) {
+	for i := 0; i < weight; i++ {
+		select {
+		case <-s.held:
+		default:
+			panic("limit: released more than held")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/limit"
+)
+
+func init() {
+	commands["limit"] = command{
+		usage: "limit [-tasks N] [-concurrent K] [-work D] [-rate R]",
+		run:   limitCmd,
+	}
+}
+
+// limitCmd starts a goroutine per task but lets only -concurrent of them
+// work at once, then starts them again at no more than -rate a second
+func limitCmd(args []string) error {
+	fs := newFlagSet("limit")
+	tasks := fs.Int("tasks", 50, "number of goroutines to start")
+	concurrent := fs.Int("concurrent", 4, "number of tasks allowed to work at once")
+	work := fs.Duration("work", 10*time.Millisecond, "how long each task takes")
+	rate := fs.Int("rate", 100, "number of tasks allowed to start per second")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tasks < 1 || *concurrent < 1 || *rate < 1 {
+		return errors.New("limit: -tasks, -concurrent and -rate must be at least 1")
+	}
+	// The limiter adds a token every second/rate, which rounds down to
+	// nothing past one a nanosecond
+	if *rate > int(time.Second) {
+		return fmt.Errorf("limit: -rate must be at most %d", int(time.Second))
+	}
+	ctx := context.Background()
+
+	sem := limit.NewSemaphore(*concurrent)
+	var running, peak atomic.Int64
+	start := time.Now()
+	hammer(*tasks, 1, func() {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			return
+		}
+		defer sem.Release(1)
+		n := running.Add(1)
+		// Keep the highest number seen; another goroutine may raise it
+		// between our Load and CompareAndSwap, so try again until it sticks
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(*work)
+		running.Add(-1)
+	})
+	fmt.Printf("Semaphore:   %d tasks, at most %d working at once (limit %d), took %v\n",
+		*tasks, peak.Load(), *concurrent, time.Since(start).Round(time.Millisecond))
+
+	// A burst of one, so the rate holds from the very first task
+	rl := limit.NewRateLimiter(time.Second/time.Duration(*rate), 1)
+	defer rl.Stop()
+	start = time.Now()
+	hammer(*tasks, 1, func() {
+		rl.Wait(ctx)
+	})
+	elapsed := time.Since(start)
+	fmt.Printf("RateLimiter: %d tasks started in %v, %.0f a second (limit %d)\n",
+		*tasks, elapsed.Round(time.Millisecond), float64(*tasks)/elapsed.Seconds(), *rate)
+
+	if peak.Load() > int64(*concurrent) {
+		return errors.New("limit: more tasks ran at once than the semaphore allows")
+	}
+	return nil
+}
@@ -1,110 +1,122 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"sync"
+	"os"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/safe"
 )
 
-type safeNumber struct {
-	val int
-	m   sync.Mutex
+func main() {
+	// With no arguments, walk through every strategy once, in order
+	if len(os.Args) < 2 {
+		demo()
+		return
+	}
+	if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+		// The flag package has already printed the usage for -h
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 }
 
-func main() {
+func demo() {
 	fmt.Println("Blocking With waitgroups")
 	// The most straightforward way of solving a data race, is to
 	// block read access until the write operation has been completed
-	fmt.Println(blockingWithWaitgroups())
+	fmt.Println(safe.BlockingWithWaitgroups())
 
 	fmt.Println("Blocking With channels")
 	// Blocking inside the getNumber function, although simple, would get
 	// troublesome if we want to call the function repeatedly. The next
 	// method follows a more flexible approach towards blocking.
-	fmt.Println(blockingWithChannel())
+	fmt.Println(safe.BlockingWithChannel())
 
 	fmt.Println("Returning a channels")
 	// The code is blocked until something gets pushed into the returned channel
 	// As opposed to the previous method, we block in the main function, instead
 	// of the function itself
-	i := <-returningWithChannel()
+	i := <-safe.ReturningWithChannel()
 	fmt.Println(i)
 
+	fmt.Println("Returning a future")
+	// A bare channel can only be read once and has no room for an error.
+	// A Future can be awaited by any number of goroutines, and returns
+	// the error too
+	fmt.Println(safe.Go(func() (int, error) { return 5, nil }).Await(context.Background()))
+
+	fmt.Println("Broadcasting to several readers")
+	// Sending on a done channel wakes a single reader. A Signal closes
+	// the channel instead, which wakes every goroutine waiting on it, and
+	// each of them can then read the number
+	fmt.Println(safe.BroadcastingWithSignal(3))
+
 	fmt.Println("Using Mutex")
 	// Until now, we had decided that the value of i should only be read after
 	// the write operation has finished. Let’s now think about the case, where
 	// we don’t care about the order of reads and writes, we only require that
 	// they do not occur simultaneously. If this sounds like your use case,
 	// then you should consider using a mutex
-	fmt.Println(useMutex())
-}
+	fmt.Println(safe.UseMutex())
 
-func blockingWithWaitgroups() int {
-	var i int
-	// Initialize a waitgroup variable
-	var wg sync.WaitGroup
-	// `Add(1) signifies that there is 1 task that we need to wait for
-	wg.Add(1)
-	go func() {
-		i = 5
-		// Calling `wg.Done` indicates that we are done with the task we are waiting fo
-		wg.Done()
-	}()
-	// `wg.Wait` blocks until `wg.Done` is called the same number of times
-	// as the amount of tasks we have (in this case, 1 time)
-	wg.Wait()
-	return i
-}
-
-func blockingWithChannel() int {
-	var i int
-	// Create a channel to push an empty struct to once we're done
-	done := make(chan struct{})
-	go func() {
-		i = 5
-		// Push an empty struct once we're done
-		done <- struct{}{}
-	}()
-	// This statement blocks until something gets pushed into the `done` channel
-	<-done
-	return i
-}
+	fmt.Println("Using atomics")
+	// For a single number, sync/atomic gives the same guarantee as the
+	// mutex without any locking at all. See ./benchmarks for how much
+	// that saves
+	fmt.Println(safe.UseAtomic())
 
-func returningWithChannel() <-chan int {
-	// create the channel
-	c := make(chan int)
-	go func() {
-		// push the result into the channel
-		c <- 5
-	}()
-	// immediately return the channel
-	return c
-}
-
-func (i *safeNumber) get() int {
-	// The `Lock` method of the mutex blocks if it is already locked
-	// if not, then it blocks other calls until the `Unlock` method is called
-	i.m.Lock()
-	// Defer `Unlock` until this method returns
-	defer i.m.Unlock()
-	// Return the value
-	return i.val
-}
+	fmt.Println("Blocking with a timeout")
+	// None of the above can give up if the writing goroutine stalls or
+	// panics. Waiting on a context as well puts a limit on how long we
+	// block, and we get an error instead of a number if it runs out
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	fmt.Println(safe.BlockingWithChannelCtx(ctx))
 
-func (i *safeNumber) set(val int) {
-	// Similar to the `Get` method, except we Lock until we are done
-	// writing to `i.val`
-	i.m.Lock()
-	defer i.m.Unlock()
-	i.val = val
+	fmt.Println("Bounded parallel fetch")
+	// Real programs rarely have a single goroutine. Here several "downloads"
+	// run at once, but never more than a fixed number together, and the
+	// first one to fail cancels the others through a shared context
+	fmt.Println(boundedFetch())
 }
 
-func useMutex() int {
-	// Create an instance of `safeNumber`
-	i := &safeNumber{}
-	// Use `Set` and `Get` instead of regular assignments and reads
-	// We can now be sure that we can read only if the write has completed, or vice versa
-	go func() {
-		i.set(5)
-	}()
-	return i.get()
+func boundedFetch() ([]int, error) {
+	// Each fetcher pretends to download something by sleeping for a bit,
+	// and the fourth one fails part way through
+	fetchers := make([]safe.FetchFunc[int], 6)
+	// Each fetcher only marks its own entry, and FetchAll has waited for
+	// all of them by the time we look, so no lock is needed
+	started := make([]bool, len(fetchers))
+	for n := range fetchers {
+		fetchers[n] = func(ctx context.Context) (int, error) {
+			started[n] = true
+			select {
+			case <-time.After(time.Duration(n) * 10 * time.Millisecond):
+				if n == 3 {
+					fmt.Printf("  fetch %d: failed\n", n)
+					return 0, fmt.Errorf("fetch %d failed", n)
+				}
+				fmt.Printf("  fetch %d: done\n", n)
+				return n * n, nil
+			case <-ctx.Done():
+				fmt.Printf("  fetch %d: cancelled\n", n)
+				return 0, ctx.Err()
+			}
+		}
+	}
+	// Run at most 2 fetchers at the same time
+	results, err := safe.FetchAll(context.Background(), 2, fetchers)
+	for n, ok := range started {
+		if !ok {
+			fmt.Printf("  fetch %d: never started\n", n)
+		}
+	}
+	return results, err
 }
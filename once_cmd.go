@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+func init() {
+	commands["once"] = command{
+		usage: "once [-callers N]",
+		run:   onceCmd,
+	}
+}
+
+// onceCmd has many goroutines ask for the same value at the same time,
+// through a Lazy and a SingleFlight, and counts how often it was computed
+func onceCmd(args []string) error {
+	fs := newFlagSet("once")
+	callers := fs.Int("callers", 500, "number of goroutines asking at once")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *callers < 1 {
+		return errors.New("once: -callers must be at least 1")
+	}
+
+	var lazyRuns atomic.Int64
+	lazy := safe.NewLazy(func() string {
+		lazyRuns.Add(1)
+		// Give the other callers time to pile up behind the first
+		time.Sleep(10 * time.Millisecond)
+		return "config"
+	})
+	hammer(*callers, 1, func() { lazy.Get() })
+	fmt.Printf("Lazy:         %d callers, init ran %d time(s)\n", *callers, lazyRuns.Load())
+
+	var flightRuns, shared atomic.Int64
+	var sf safe.SingleFlight[string, int]
+	lookup := func() (int, error) {
+		flightRuns.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return 42, nil
+	}
+	hammer(*callers, 1, func() {
+		if _, _, s := sf.Do("answer", lookup); s {
+			shared.Add(1)
+		}
+	})
+	fmt.Printf("SingleFlight: %d callers, lookup ran %d time(s), %d shared a result\n",
+		*callers, flightRuns.Load(), shared.Load())
+
+	if lazyRuns.Load() != 1 {
+		return errors.New("once: Lazy ran its init more than once")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package pool runs tasks on a fixed number of worker goroutines.
+//
+// Starting a goroutine per task is cheap, but not free: with no limit, a
+// burst of work can start hundreds of thousands of them at once, each
+// holding its stack and whatever it references, and a caller that
+// doesn't wait for them has no idea when, or whether, they finish. A
+// Pool caps how many tasks run at once and can be shut down cleanly.
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when submitting to a pool that is shutting down.
+var ErrClosed = errors.New("pool: closed")
+
+// Pool runs submitted tasks on a fixed set of worker goroutines.
+type Pool struct {
+	tasks   chan func()
+	workers sync.WaitGroup
+
+	// Closed by Shutdown. Submit and the workers select on it alongside
+	// `tasks`, so nobody is left blocked on a send or receive that will
+	// never happen, and `tasks` itself never needs closing
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// NewPool starts a pool with the given number of workers.
+func NewPool(workers int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &Pool{tasks: make(chan func()), quit: make(chan struct{})}
+	p.workers.Add(workers)
+	for w := 0; w < workers; w++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit hands task to a worker, waiting until one is free, and returns
+// without waiting for the task to run. It returns ErrClosed once Shutdown
+// has been called, including to a Submit that was still waiting for a
+// worker.
+func (p *Pool) Submit(task func()) error {
+	// A select picks at random among ready cases, so check `quit` on its
+	// own first, or a free worker could still take the task after Shutdown
+	select {
+	case <-p.quit:
+		return ErrClosed
+	default:
+	}
+	select {
+	case p.tasks <- task:
+		return nil
+	case <-p.quit:
+		return ErrClosed
+	}
+}
+
+// SubmitWait is like Submit, but also waits for task to finish.
+func (p *Pool) SubmitWait(task func()) error {
+	done := make(chan struct{})
+	err := p.Submit(func() {
+		defer close(done)
+		task()
+	})
+	if err != nil {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// Shutdown stops the pool from accepting tasks and waits for the ones
+// already handed to a worker to finish. If ctx is done first it returns
+// ctx.Err(); the workers then carry on in the background until their
+// tasks return.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.quitOnce.Do(func() {
+		close(p.quit)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work() {
+	defer p.workers.Done()
+	for {
+		select {
+		case task := <-p.tasks:
+			task()
+		case <-p.quit:
+			// Each worker exits once it has finished its current task
+			return
+		}
+	}
+}
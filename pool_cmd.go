@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/pool"
+)
+
+func init() {
+	commands["pool"] = command{
+		usage: "pool [-tasks N] [-workers N] [-work D]",
+		run:   poolCmd,
+	}
+}
+
+// poolCmd runs the same batch of tasks twice: once with a goroutine per
+// task, and once on a pool, and shows how many goroutines each needed
+func poolCmd(args []string) error {
+	fs := newFlagSet("pool")
+	tasks := fs.Int("tasks", 10000, "number of tasks to run")
+	workers := fs.Int("workers", 8, "number of workers in the pool")
+	work := fs.Duration("work", time.Millisecond, "how long each task takes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tasks < 1 || *workers < 1 {
+		return errors.New("pool: -tasks and -workers must be at least 1")
+	}
+	task := func(done *atomic.Int64) func() {
+		return func() {
+			time.Sleep(*work)
+			done.Add(1)
+		}
+	}
+
+	fmt.Println("Unbounded goroutines")
+	// Starting a goroutine per task never blocks, so the loop finishes
+	// straight away, with every task still running and nothing keeping
+	// track of them
+	var unbounded atomic.Int64
+	stop := watchGoroutines()
+	start := time.Now()
+	for i := 0; i < *tasks; i++ {
+		go task(&unbounded)()
+	}
+	fmt.Printf("  loop returned after %v with %d of %d tasks finished\n",
+		time.Since(start).Round(time.Microsecond), unbounded.Load(), *tasks)
+	// We only wait here so the numbers below aren't mixed up with the
+	// pool's; a real caller that forgot to would just leak them
+	for unbounded.Load() < int64(*tasks) {
+		time.Sleep(time.Millisecond)
+	}
+	fmt.Printf("  all done after %v, peak of %d goroutines\n", time.Since(start).Round(time.Microsecond), stop())
+
+	fmt.Printf("Pool of %d workers\n", *workers)
+	var pooled atomic.Int64
+	stop = watchGoroutines()
+	start = time.Now()
+	p := pool.NewPool(*workers)
+	for i := 0; i < *tasks; i++ {
+		p.Submit(task(&pooled))
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		return err
+	}
+	fmt.Printf("  all %d tasks done after %v, peak of %d goroutines\n",
+		pooled.Load(), time.Since(start).Round(time.Microsecond), stop())
+	return nil
+}
+
+// watchGoroutines samples runtime.NumGoroutine until the returned func is
+// called, which returns the highest count seen
+func watchGoroutines() (stop func() int) {
+	var peak atomic.Int64
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(100 * time.Microsecond)
+		defer ticker.Stop()
+		for {
+			if n := int64(runtime.NumGoroutine()); n > peak.Load() {
+				peak.Store(n)
+			}
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() int {
+		close(quit)
+		<-done
+		return int(peak.Load())
+	}
+}
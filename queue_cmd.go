@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+func init() {
+	commands["queue"] = command{
+		usage: "queue [-producers N] [-consumers N] [-items N] [-capacity N]",
+		run:   queueCmd,
+	}
+}
+
+// queueCmd has producers and consumers pass numbers through a small
+// BoundedQueue, then checks that every number came out exactly once
+func queueCmd(args []string) error {
+	fs := newFlagSet("queue")
+	producers := fs.Int("producers", 4, "number of goroutines putting items")
+	consumers := fs.Int("consumers", 4, "number of goroutines getting items")
+	items := fs.Int("items", 100000, "number of items each producer puts")
+	capacity := fs.Int("capacity", 8, "capacity of the queue")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *producers < 1 || *consumers < 1 || *items < 1 {
+		return errors.New("queue: -producers, -consumers and -items must be at least 1")
+	}
+
+	q := safe.NewBoundedQueue[int](*capacity)
+	total := *producers * *items
+	// Each consumer counts what it sees in its own slice, so counting
+	// needs no lock; the slices are only added up after everyone is done
+	seen := make([][]int, *consumers)
+	// Consumers can't tell on their own when the last item has been
+	// taken, so they stop when this context is cancelled. That happens
+	// once the producers are done and the queue is empty, not once every
+	// item has been counted: if the queue lost one, we want to report it
+	// as missing rather than wait forever for it
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for c := 0; c < *consumers; c++ {
+		seen[c] = make([]int, total)
+		wg.Add(1)
+		go func(seen []int) {
+			defer wg.Done()
+			for {
+				v, err := q.Dequeue(ctx)
+				if err != nil {
+					return
+				}
+				seen[v]++
+			}
+		}(seen[c])
+	}
+	var producing sync.WaitGroup
+	for p := 0; p < *producers; p++ {
+		producing.Add(1)
+		go func(p int) {
+			defer producing.Done()
+			// Put blocks whenever the queue is full, which keeps the
+			// producers from running ahead of the consumers
+			for i := 0; i < *items; i++ {
+				q.Put(p**items + i)
+			}
+		}(p)
+	}
+	producing.Wait()
+	// Nothing is added from here on, so once the queue is empty every
+	// item has been handed to a consumer, and a consumer counts its item
+	// before it next looks at ctx
+	safe.WaitForCondition(context.Background(), time.Millisecond, func() bool { return q.Len() == 0 })
+	cancel()
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	missing, duplicated := 0, 0
+	for v := 0; v < total; v++ {
+		n := 0
+		for c := range seen {
+			n += seen[c][v]
+		}
+		switch {
+		case n == 0:
+			missing++
+		case n > 1:
+			duplicated++
+		}
+	}
+	fmt.Printf("%d items through a queue of %d in %v (%v/item): %d missing, %d duplicated\n",
+		total, *capacity, elapsed.Round(time.Microsecond), elapsed/time.Duration(total), missing, duplicated)
+	if missing > 0 || duplicated > 0 {
+		return errors.New("queue: items were lost or duplicated")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package racy
+
+import "sync"
+
+// LazyPointer is safe.LazyPointer done the classic wrong way:
+// double-checked locking with a plain pointer.
+//
+// The value is filled in before the pointer is set, so in program order
+// it looks fine. But the first check reads `p` without the lock, and the
+// Go memory model gives that read no guarantees: a goroutine that sees
+// the new pointer is not promised to see the writes init made before it.
+// The compiler and CPU may reorder them. On amd64, which keeps stores in
+// order, this rarely shows, which is exactly why it slips through; the
+// race detector does report it.
+type LazyPointer[T any] struct {
+	m    sync.Mutex
+	p    *T
+	init func(*T)
+}
+
+func NewLazyPointer[T any](init func(*T)) *LazyPointer[T] {
+	return &LazyPointer[T]{init: init}
+}
+
+func (l *LazyPointer[T]) Get() *T {
+	// This read races with the write below
+	if l.p == nil {
+		l.m.Lock()
+		if l.p == nil {
+			p := new(T)
+			l.init(p)
+			l.p = p
+		}
+		l.m.Unlock()
+	}
+	return l.p
+}
+
+// PublishFirstPointer is a plainer bug that often comes along with
+// double-checked locking: the pointer is set first and the value filled
+// in afterwards, through it. Anyone who checks in between sees a
+// half-built value, whatever the memory model says, since that is
+// simply the order the program does things in.
+type PublishFirstPointer[T any] struct {
+	m    sync.Mutex
+	p    *T
+	init func(*T)
+}
+
+func NewPublishFirstPointer[T any](init func(*T)) *PublishFirstPointer[T] {
+	return &PublishFirstPointer[T]{init: init}
+}
+
+func (l *PublishFirstPointer[T]) Get() *T {
+	if l.p == nil {
+		l.m.Lock()
+		if l.p == nil {
+			// Published here, before init has filled anything in
+			l.p = new(T)
+			l.init(l.p)
+		}
+		l.m.Unlock()
+	}
+	return l.p
+}
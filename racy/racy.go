@@ -0,0 +1,96 @@
+// Package racy holds broken versions of the strategies in package safe,
+// with the synchronization taken out. Each function has a data race on
+// purpose.
+//
+// Without the race detector they usually appear to work, which is exactly
+// what makes data races dangerous. Run them with it turned on to see the
+// report, and compare with the fixed versions:
+//
+//	go run -race . run -racy mutex
+//	go run -race . run mutex
+package racy
+
+// BlockingWithWaitgroups is safe.BlockingWithWaitgroups without the
+// WaitGroup: nothing makes the read wait for the write.
+func BlockingWithWaitgroups() int {
+	var i int
+	go func() {
+		i = 5
+	}()
+	// This read races with the write above
+	return i
+}
+
+// BlockingWithChannel is safe.BlockingWithChannel without the done
+// channel.
+func BlockingWithChannel() int {
+	var i int
+	go func() {
+		i = 5
+	}()
+	return i
+}
+
+// ReturningWithChannel returns a channel like safe.ReturningWithChannel,
+// but fills it from a variable that another goroutine is still writing,
+// instead of sending the value from that goroutine.
+func ReturningWithChannel() <-chan int {
+	var i int
+	c := make(chan int, 1)
+	go func() {
+		i = 5
+	}()
+	// The channel only hands over what we read here, and that read
+	// itself races with the write
+	c <- i
+	return c
+}
+
+// number is safe.SafeValue[int] without the mutex
+type number struct {
+	val int
+}
+
+func (n *number) get() int {
+	return n.val
+}
+
+func (n *number) set(val int) {
+	n.val = val
+}
+
+// UseMutex is safe.UseMutex with a number that has no mutex.
+func UseMutex() int {
+	i := &number{}
+	go func() {
+		i.set(5)
+	}()
+	return i.get()
+}
+
+// UseAtomic is safe.UseAtomic with a plain int64 in place of the
+// AtomicNumber.
+func UseAtomic() int64 {
+	var i int64
+	go func() {
+		i = 5
+	}()
+	return i
+}
+
+// Counter is safe.SafeCounter built on a plain int64. Increments from
+// different goroutines overwrite each other, and some are lost.
+type Counter struct {
+	val int64
+}
+
+func (c *Counter) Inc() int64 {
+	// Read, add and write are three separate steps here, and another
+	// goroutine can slip in between them
+	c.val++
+	return c.val
+}
+
+func (c *Counter) Value() int64 {
+	return c.val
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/racy"
+	"github.com/abyanjksatu/race-condition/safe"
+	"github.com/abyanjksatu/race-condition/trace"
+)
+
+// strategies lists the ways of reading a number written by another
+// goroutine, in the order the demo introduces them. `get` may be given a
+// nil recorder to run untraced; `racy` is the same strategy with its
+// synchronization removed, and can't be traced
+var strategies = []struct {
+	name string
+	get  func(rec *trace.Recorder) int
+	racy func() int
+}{
+	{
+		"waitgroup",
+		func(rec *trace.Recorder) int { return safe.BlockingWithWaitgroups(rec) },
+		racy.BlockingWithWaitgroups,
+	},
+	{
+		"channel",
+		func(rec *trace.Recorder) int { return safe.BlockingWithChannel(rec) },
+		racy.BlockingWithChannel,
+	},
+	{
+		"returned-channel",
+		func(rec *trace.Recorder) int {
+			i := <-safe.ReturningWithChannel(rec)
+			rec.Recordf("received %d", i)
+			return i
+		},
+		func() int { return <-racy.ReturningWithChannel() },
+	},
+	{
+		"mutex",
+		func(rec *trace.Recorder) int { return safe.UseMutex(rec) },
+		racy.UseMutex,
+	},
+	{
+		"atomic",
+		func(rec *trace.Recorder) int { return int(safe.UseAtomic(rec)) },
+		func() int { return int(racy.UseAtomic()) },
+	},
+}
+
+func init() {
+	commands["run"] = command{
+		usage: "run <" + strings.Join(strategyNames(), "|") + "|all> [flags]",
+		run:   runStrategiesCmd,
+	}
+}
+
+func strategyNames() []string {
+	names := make([]string, len(strategies))
+	for i, s := range strategies {
+		names[i] = s.name
+	}
+	return names
+}
+
+func runStrategiesCmd(args []string) error {
+	fs := newFlagSet("run")
+	goroutines := fs.Int("goroutines", 1, "number of goroutines running the strategy at once")
+	iterations := fs.Int("iterations", 1, "number of times each goroutine runs the strategy")
+	broken := fs.Bool("racy", false, "run the broken, unsynchronized version (try it with go run -race)")
+	traceFormat := fs.String("trace", "", "print a trace of every step, as a \"text\" timeline or \"json\"")
+	name, err := parseWithArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("run: missing strategy, one of " + strings.Join(strategyNames(), ", ") + " or all")
+	}
+	if *goroutines < 1 || *iterations < 1 {
+		return errors.New("run: -goroutines and -iterations must be at least 1")
+	}
+	switch *traceFormat {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("run: unknown trace format %q, want text or json", *traceFormat)
+	}
+	if *broken && *traceFormat != "" {
+		// The recorder takes a lock on every event, which would order
+		// the racing accesses and hide the very race we want to show
+		return errors.New("run: -racy can't be traced, the tracing itself would hide the race")
+	}
+
+	for _, s := range strategies {
+		if name == "all" || name == s.name {
+			var rec *trace.Recorder
+			if *traceFormat != "" {
+				rec = trace.NewRecorder()
+			}
+			get := func() int { return s.get(rec) }
+			if *broken {
+				get = s.racy
+			}
+			runStrategy(s.name, get, *goroutines, *iterations)
+			if err := printTrace(rec, *traceFormat); err != nil {
+				return err
+			}
+			if name != "all" {
+				return nil
+			}
+		}
+	}
+	if name != "all" {
+		return fmt.Errorf("run: unknown strategy %q", name)
+	}
+	return nil
+}
+
+// runStrategy calls get iterations times from each of goroutines
+// goroutines, then prints how long that took and which values were read.
+func runStrategy(name string, get func() int, goroutines, iterations int) {
+	var (
+		m      sync.Mutex
+		counts = make(map[int]int)
+		wg     sync.WaitGroup
+	)
+	start := time.Now()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Count into a local map and merge once at the end, so the
+			// goroutines don't spend the run contending on `m`
+			local := make(map[int]int)
+			for i := 0; i < iterations; i++ {
+				local[get()]++
+			}
+			m.Lock()
+			defer m.Unlock()
+			for v, n := range local {
+				counts[v] += n
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	runs := goroutines * iterations
+	values := make([]int, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	results := make([]string, len(values))
+	for i, v := range values {
+		results[i] = fmt.Sprintf("%d×%d", v, counts[v])
+	}
+	fmt.Printf("%s: %d runs in %v (%v/run), read %s\n",
+		name, runs, elapsed.Round(time.Microsecond), elapsed/time.Duration(runs), strings.Join(results, " "))
+}
+
+func printTrace(rec *trace.Recorder, format string) error {
+	switch {
+	case rec == nil:
+		return nil
+	case format == "json":
+		return rec.WriteJSON(os.Stdout)
+	default:
+		if err := rec.WriteTimeline(os.Stdout); err != nil {
+			return err
+		}
+		fmt.Println()
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package safe
+
+import "sync"
+
+// Actor runs functions one at a time on a single goroutine that it owns.
+//
+// Any state that is only touched from inside functions passed to Send is
+// never accessed by two goroutines at once, so it needs no lock of its
+// own. This is the "share memory by communicating" alternative to the
+// mutex in SafeValue.
+type Actor struct {
+	mailbox chan func()
+	done    chan struct{}
+
+	// `m` keeps Send from racing with Stop closing the mailbox, since
+	// sending on a closed channel panics
+	m       sync.RWMutex
+	stopped bool
+}
+
+// NewActor starts an Actor whose mailbox holds up to buffer pending
+// functions before Send starts blocking.
+func NewActor(buffer int) *Actor {
+	a := &Actor{
+		mailbox: make(chan func(), buffer),
+		done:    make(chan struct{}),
+	}
+	go a.loop()
+	return a
+}
+
+// Send queues fn to run on the actor's goroutine. It returns false, and
+// fn never runs, if the actor has been stopped.
+func (a *Actor) Send(fn func()) bool {
+	a.m.RLock()
+	defer a.m.RUnlock()
+	if a.stopped {
+		return false
+	}
+	a.mailbox <- fn
+	return true
+}
+
+// Stop runs everything already queued, then shuts the actor down. It
+// returns once the last function has finished.
+func (a *Actor) Stop() {
+	a.m.Lock()
+	if !a.stopped {
+		a.stopped = true
+		close(a.mailbox)
+	}
+	a.m.Unlock()
+	<-a.done
+}
+
+func (a *Actor) loop() {
+	defer close(a.done)
+	// `range` keeps receiving until the mailbox is closed and empty, so
+	// nothing sent before Stop is lost
+	for fn := range a.mailbox {
+		fn()
+	}
+}
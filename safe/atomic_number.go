@@ -0,0 +1,32 @@
+package safe
+
+import "sync/atomic"
+
+// AtomicNumber is an int64 that can be read and written from many
+// goroutines, like SafeValue[int64], but built on sync/atomic instead of
+// a mutex. Each operation is a single CPU instruction rather than a
+// lock and unlock, which makes it much cheaper under contention. The
+// price is that only these few operations are available; anything more
+// involved needs CompareAndSwap in a loop, or a mutex.
+type AtomicNumber struct {
+	val atomic.Int64
+}
+
+func (n *AtomicNumber) Load() int64 {
+	return n.val.Load()
+}
+
+func (n *AtomicNumber) Store(val int64) {
+	n.val.Store(val)
+}
+
+// Add adds delta and returns the new value.
+func (n *AtomicNumber) Add(delta int64) int64 {
+	return n.val.Add(delta)
+}
+
+// CompareAndSwap sets the value to new only if it is currently old, and
+// reports whether it did.
+func (n *AtomicNumber) CompareAndSwap(old, new int64) bool {
+	return n.val.CompareAndSwap(old, new)
+}
@@ -0,0 +1,96 @@
+package safe
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// BitSet is a set of non-negative integers that many goroutines can
+// update at once. It grows as needed to fit the largest bit set.
+//
+// Bits are stored 64 to a word. Changing a bit is a compare-and-swap loop
+// on its word, so goroutines only get in each other's way when they hit
+// the same word at the same moment, and even then nobody blocks. Growing
+// swaps in a bigger slice of words, which must not happen while someone
+// is in the middle of a CAS on the old one; the RWMutex is held shared by
+// every bit operation and exclusively by growth to make sure of that.
+type BitSet struct {
+	m     sync.RWMutex
+	words []atomic.Uint64
+}
+
+// Set adds i to the set.
+func (b *BitSet) Set(i int) {
+	w, mask := bitPos(i)
+	b.m.RLock()
+	if w >= len(b.words) {
+		b.m.RUnlock()
+		b.grow(w + 1)
+		b.m.RLock()
+	}
+	defer b.m.RUnlock()
+	word := &b.words[w]
+	for {
+		old := word.Load()
+		// If another goroutine changed the word between our Load and
+		// CAS, the CAS fails and we try again with the new value
+		if old&mask != 0 || word.CompareAndSwap(old, old|mask) {
+			return
+		}
+	}
+}
+
+// Clear removes i from the set.
+func (b *BitSet) Clear(i int) {
+	w, mask := bitPos(i)
+	b.m.RLock()
+	defer b.m.RUnlock()
+	if w >= len(b.words) {
+		return
+	}
+	word := &b.words[w]
+	for {
+		old := word.Load()
+		if old&mask == 0 || word.CompareAndSwap(old, old&^mask) {
+			return
+		}
+	}
+}
+
+// Test reports whether i is in the set.
+func (b *BitSet) Test(i int) bool {
+	w, mask := bitPos(i)
+	b.m.RLock()
+	defer b.m.RUnlock()
+	if w >= len(b.words) {
+		return false
+	}
+	return b.words[w].Load()&mask != 0
+}
+
+// grow makes room for at least n words.
+func (b *BitSet) grow(n int) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	// Another goroutine may have grown the set while we waited
+	if n <= len(b.words) {
+		return
+	}
+	// Double each time so a run of increasing Sets isn't quadratic
+	size := 2 * len(b.words)
+	if size < n {
+		size = n
+	}
+	words := make([]atomic.Uint64, size)
+	for i := range b.words {
+		words[i].Store(b.words[i].Load())
+	}
+	b.words = words
+}
+
+func bitPos(i int) (word int, mask uint64) {
+	if i < 0 {
+		panic("BitSet: negative index")
+	}
+	return i / 64, 1 << (uint(i) % 64)
+}
@@ -0,0 +1,74 @@
+package safe
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is how BoundedGroup reports a task that panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", p.Value)
+}
+
+// BoundedGroup runs tasks in the background, at most a fixed number at a
+// time, and collects every error they return. A panicking task does not
+// bring the program down; its panic is recovered and reported as a
+// *PanicError alongside the ordinary errors.
+type BoundedGroup struct {
+	// Buffered channel used as a semaphore, one slot per running task
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	m    sync.Mutex
+	errs []error
+}
+
+// NewBoundedGroup returns a group that runs at most max tasks at once.
+func NewBoundedGroup(max int) *BoundedGroup {
+	if max < 1 {
+		max = 1
+	}
+	return &BoundedGroup{slots: make(chan struct{}, max)}
+}
+
+// Go runs fn in a new goroutine, first waiting for a free slot if max
+// tasks are already running.
+func (g *BoundedGroup) Go(fn func() error) {
+	g.slots <- struct{}{}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() { <-g.slots }()
+		// This deferred func runs first, so a panic is recorded before
+		// the slot is released and Wait is told we are done
+		defer func() {
+			if r := recover(); r != nil {
+				g.record(&PanicError{Value: r, Stack: debug.Stack()})
+			}
+		}()
+		if err := fn(); err != nil {
+			g.record(err)
+		}
+	}()
+}
+
+// Wait blocks until every task started with Go has finished, and returns
+// all of their errors in the order they happened.
+func (g *BoundedGroup) Wait() []error {
+	g.wg.Wait()
+	g.m.Lock()
+	defer g.m.Unlock()
+	return append([]error(nil), g.errs...)
+}
+
+func (g *BoundedGroup) record(err error) {
+	g.m.Lock()
+	defer g.m.Unlock()
+	g.errs = append(g.errs, err)
+}
@@ -0,0 +1,148 @@
+package safe
+
+import (
+	"context"
+	"sync"
+)
+
+// BoundedQueue is a fixed-capacity FIFO queue built on sync.Cond. Put
+// waits while the queue is full and Get waits while it is empty; Enqueue
+// and Dequeue do the same, but also give up when their context is
+// cancelled.
+type BoundedQueue[T any] struct {
+	m sync.Mutex
+	// `notFull` and `notEmpty` both use `m`. Waiting on a condition
+	// variable releases the mutex, and it is held again when Wait
+	// returns
+	notFull  *sync.Cond
+	notEmpty *sync.Cond
+
+	// `buf` is a ring buffer: `head` is the oldest item and `count` is
+	// how many slots after it are in use
+	buf   []T
+	head  int
+	count int
+}
+
+// NewBoundedQueue returns an empty queue that holds up to capacity items.
+func NewBoundedQueue[T any](capacity int) *BoundedQueue[T] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	q := &BoundedQueue[T]{buf: make([]T, capacity)}
+	q.notFull = sync.NewCond(&q.m)
+	q.notEmpty = sync.NewCond(&q.m)
+	return q
+}
+
+// Put adds v to the back of the queue, waiting for room if it is full.
+func (q *BoundedQueue[T]) Put(v T) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	// This is the classic condition variable loop. `Wait` can return
+	// even though the queue is still full: another Put may have taken
+	// the slot first, or the wake-up may be spurious. So the condition
+	// is always checked again, never assumed
+	for q.count == len(q.buf) {
+		q.notFull.Wait()
+	}
+	q.push(v)
+}
+
+// Get removes and returns the item at the front of the queue, waiting
+// for one if it is empty.
+func (q *BoundedQueue[T]) Get() T {
+	q.m.Lock()
+	defer q.m.Unlock()
+	for q.count == 0 {
+		q.notEmpty.Wait()
+	}
+	return q.pop()
+}
+
+// Enqueue adds v to the back of the queue, waiting for room if it is
+// full. It returns ctx.Err() if ctx is cancelled before there is room.
+func (q *BoundedQueue[T]) Enqueue(ctx context.Context, v T) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+	// A condition variable can't be used in a select, so instead we have
+	// the context wake every waiter when it is cancelled, and each one
+	// checks whether it was its own context that ended
+	stop := context.AfterFunc(ctx, q.wakeAll)
+	defer stop()
+	for q.count == len(q.buf) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		q.notFull.Wait()
+		q.passOn(ctx, q.notFull)
+	}
+	q.push(v)
+	return nil
+}
+
+// Dequeue removes and returns the item at the front of the queue, waiting
+// for one if it is empty. It returns ctx.Err() if ctx is cancelled before
+// an item arrives.
+func (q *BoundedQueue[T]) Dequeue(ctx context.Context) (T, error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	stop := context.AfterFunc(ctx, q.wakeAll)
+	defer stop()
+	for q.count == 0 {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+		q.notEmpty.Wait()
+		q.passOn(ctx, q.notEmpty)
+	}
+	return q.pop(), nil
+}
+
+// Len returns the number of items currently in the queue.
+func (q *BoundedQueue[T]) Len() int {
+	q.m.Lock()
+	defer q.m.Unlock()
+	return q.count
+}
+
+// push adds v to the back, and must be called with `m` held and room in
+// the queue.
+func (q *BoundedQueue[T]) push(v T) {
+	q.buf[(q.head+q.count)%len(q.buf)] = v
+	q.count++
+	// Exactly one item was added, so waking one Get is enough
+	q.notEmpty.Signal()
+}
+
+// pop removes the front item, and must be called with `m` held and the
+// queue not empty.
+func (q *BoundedQueue[T]) pop() T {
+	v := q.buf[q.head]
+	// Clear the slot so the queue doesn't keep the value alive
+	var zero T
+	q.buf[q.head] = zero
+	q.head = (q.head + 1) % len(q.buf)
+	q.count--
+	q.notFull.Signal()
+	return v
+}
+
+// passOn hands a wake-up to another waiter if ctx has ended. A Signal
+// meant for a live waiter may have woken us instead, and we are about to
+// return an error rather than use it.
+func (q *BoundedQueue[T]) passOn(ctx context.Context, c *sync.Cond) {
+	if ctx.Err() != nil {
+		c.Signal()
+	}
+}
+
+func (q *BoundedQueue[T]) wakeAll() {
+	// Taking the lock first makes sure a waiter that has just checked
+	// ctx.Err() is already inside Wait, and so doesn't miss the wake-up
+	q.m.Lock()
+	defer q.m.Unlock()
+	q.notFull.Broadcast()
+	q.notEmpty.Broadcast()
+}
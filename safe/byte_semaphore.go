@@ -0,0 +1,89 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrExceedsBudget is returned by ByteSemaphore.Acquire for a request that
+// could never be granted because it is bigger than the whole budget.
+var ErrExceedsBudget = errors.New("acquire exceeds semaphore budget")
+
+// ByteSemaphore bounds how many bytes concurrent operations hold at once,
+// for example the total size of buffers being uploaded in parallel. A
+// plain semaphore counts operations; this one lets each take a different
+// share of the budget.
+//
+// Waiters are woken whenever bytes are released and take them in no
+// particular order, so a large request can be passed over by a stream of
+// small ones.
+type ByteSemaphore struct {
+	m     sync.Mutex
+	freed *sync.Cond
+	total int64
+	used  int64
+}
+
+// NewByteSemaphore returns a semaphore with a budget of total bytes.
+func NewByteSemaphore(total int64) *ByteSemaphore {
+	s := &ByteSemaphore{total: total}
+	s.freed = sync.NewCond(&s.m)
+	return s
+}
+
+// Acquire reserves n bytes, waiting until enough are free. It returns
+// ErrExceedsBudget right away if n is more than the total budget, and
+// ctx.Err() if ctx is done before the bytes become available. Acquiring
+// zero bytes does nothing; a negative n is a bug in the caller, and
+// panics, since it would quietly grow the budget.
+func (s *ByteSemaphore) Acquire(ctx context.Context, n int64) error {
+	if n < 0 {
+		panic("ByteSemaphore: negative acquire")
+	}
+	if n == 0 {
+		return nil
+	}
+	if n > s.total {
+		return ErrExceedsBudget
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	// Same trick as BoundedQueue: the context wakes all waiters when it
+	// is cancelled, since a Cond can't be used in a select
+	stop := context.AfterFunc(ctx, func() {
+		s.m.Lock()
+		defer s.m.Unlock()
+		s.freed.Broadcast()
+	})
+	defer stop()
+	for s.used+n > s.total {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.freed.Wait()
+	}
+	s.used += n
+	return nil
+}
+
+// Release returns n bytes reserved by Acquire to the budget. Releasing
+// zero bytes does nothing; a negative n panics, like releasing more than
+// was acquired.
+func (s *ByteSemaphore) Release(n int64) {
+	if n < 0 {
+		panic("ByteSemaphore: negative release")
+	}
+	if n == 0 {
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.used -= n
+	if s.used < 0 {
+		panic("ByteSemaphore: released more than acquired")
+	}
+	// Every waiter has to check again, since the freed bytes may be
+	// enough for several small requests, or for none of them
+	s.freed.Broadcast()
+}
@@ -0,0 +1,51 @@
+package safe
+
+import (
+	"context"
+	"time"
+)
+
+// Cancellable runs fn in a new goroutine and waits for its result, but
+// stops waiting and returns ctx.Err() if ctx is done first.
+//
+// Go has no way to stop a goroutine from the outside, so when ctx wins
+// fn keeps running in the background until it returns on its own; its
+// result is then thrown away. Only the caller is freed. If fn can run
+// forever, each cancelled call leaks a goroutine, so prefer passing the
+// context into fn itself when the blocking code supports it.
+func Cancellable[T any](ctx context.Context, fn func() T) (T, error) {
+	// Buffered so the goroutine can always deliver its result and exit,
+	// even after we have stopped listening
+	c := make(chan T, 1)
+	go func() {
+		c <- fn()
+	}()
+	select {
+	case v := <-c:
+		return v, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// WithFallback runs primary in a new goroutine and returns its result if
+// it finishes within d. Otherwise it calls fallback and returns that
+// instead.
+//
+// As with Cancellable, a primary that misses the deadline is not stopped;
+// it carries on in the background and its result is thrown away.
+func WithFallback[T any](d time.Duration, primary func() T, fallback func() T) T {
+	c := make(chan T, 1)
+	go func() {
+		c <- primary()
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case v := <-c:
+		return v
+	case <-timer.C:
+		return fallback()
+	}
+}
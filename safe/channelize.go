@@ -0,0 +1,87 @@
+package safe
+
+import "sync"
+
+// Channelize turns a callback-based API into a channel. register is
+// called once with a callback to subscribe to the source, and must return
+// a function that unsubscribes it.
+//
+// Every value passed to the callback is delivered on the returned
+// channel, in order for any one calling goroutine. The callback blocks
+// until the value has been received, so a slow reader slows the source
+// down rather than values piling up in memory. The exception is values
+// passed while register itself is running, which are held until it
+// returns and then delivered first. Calling the returned cancel
+// unsubscribes, stops the forwarding goroutine and closes the channel;
+// callbacks that arrive afterwards are dropped.
+func Channelize[T any](register func(cb func(T)) (cancel func())) (<-chan T, func()) {
+	in := make(chan T)
+	out := make(chan T)
+	done := make(chan struct{})
+
+	// Nobody can read `out` until we return, so a callback made while
+	// register is still running, such as a source replaying its current
+	// value on subscribe, can't be delivered yet. Those are kept in
+	// `early` instead, and sent before anything else
+	var (
+		m           sync.Mutex
+		registering = true
+		early       []T
+	)
+	unregister := register(func(v T) {
+		m.Lock()
+		if registering {
+			early = append(early, v)
+			m.Unlock()
+			return
+		}
+		m.Unlock()
+		select {
+		case in <- v:
+		case <-done:
+		}
+	})
+	m.Lock()
+	registering = false
+	pending := early
+	early = nil
+	m.Unlock()
+
+	// Only this goroutine ever sends on `out`, which makes it the one
+	// place that can safely close it. The callbacks can run on any
+	// goroutine, at any time, so they must never touch `out` directly
+	go func() {
+		defer close(out)
+		for _, v := range pending {
+			select {
+			case out <- v:
+			case <-done:
+				return
+			}
+		}
+		for {
+			select {
+			case v := <-in:
+				select {
+				case out <- v:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			// Release any callback stuck waiting to send before
+			// unsubscribing, in case the source waits for running
+			// callbacks to return
+			close(done)
+			unregister()
+		})
+	}
+	return out, cancel
+}
@@ -0,0 +1,112 @@
+package safe
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOpen is returned by CircuitBreaker.Execute when the breaker is
+// rejecting calls.
+var ErrOpen = errors.New("circuit breaker is open")
+
+const (
+	breakerClosed int32 = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker stops calling a failing dependency for a while so it has
+// a chance to recover.
+//
+// It starts closed and lets every call through. After threshold failures
+// in a row it opens and rejects calls with ErrOpen. Once cooldown has
+// passed, a single call is let through to probe: if it succeeds the
+// breaker closes again, and if it fails the breaker reopens for another
+// cooldown. All state lives in atomics, so no call waits on a lock.
+type CircuitBreaker struct {
+	threshold int64
+	cooldown  time.Duration
+
+	state    atomic.Int32
+	failures atomic.Int64
+	// `openedAt` is in Unix nanoseconds; it is always written before
+	// `state` is switched to open, so anyone who sees the open state
+	// also sees when it happened
+	openedAt atomic.Int64
+}
+
+// NewCircuitBreaker returns a closed breaker that opens after threshold
+// consecutive failures and probes again after cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: int64(threshold), cooldown: cooldown}
+}
+
+// Execute calls fn unless the breaker is open, and records the outcome.
+// It returns ErrOpen without calling fn when the breaker is rejecting
+// calls, and otherwise whatever fn returns.
+func (b *CircuitBreaker) Execute(fn func() error) error {
+	probe := false
+	switch b.state.Load() {
+	case breakerOpen:
+		if time.Since(time.Unix(0, b.openedAt.Load())) < b.cooldown {
+			return ErrOpen
+		}
+		// Many callers can notice the cooldown is over at the same
+		// time, but only the one whose CAS wins gets to probe
+		if !b.state.CompareAndSwap(breakerOpen, breakerHalfOpen) {
+			return ErrOpen
+		}
+		probe = true
+	case breakerHalfOpen:
+		// A probe is already in flight
+		return ErrOpen
+	}
+
+	// A panicking probe would skip everything below and leave the breaker
+	// half-open, rejecting every call, forever. If fn doesn't return, open
+	// the breaker again on the way out and let the panic carry on
+	returned := false
+	if probe {
+		defer func() {
+			if !returned {
+				b.trip()
+			}
+		}()
+	}
+	err := fn()
+	returned = true
+	if err != nil {
+		if probe || b.failures.Add(1) >= b.threshold {
+			b.trip()
+		}
+		return err
+	}
+	b.failures.Store(0)
+	if probe {
+		b.state.Store(breakerClosed)
+	}
+	return nil
+}
+
+// State reports whether the breaker is currently "closed", "open" or
+// "half-open".
+func (b *CircuitBreaker) State() string {
+	switch b.state.Load() {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+func (b *CircuitBreaker) trip() {
+	b.openedAt.Store(time.Now().UnixNano())
+	b.failures.Store(0)
+	b.state.Store(breakerOpen)
+}
@@ -0,0 +1,40 @@
+package safe
+
+import (
+	"context"
+	"sync"
+)
+
+// Closer runs a cleanup function exactly once, either when Close is
+// called or when a context is cancelled, whichever happens first. Code
+// that releases a resource on both paths is a common source of
+// double-close bugs; with a Closer both paths can simply trigger it.
+type Closer struct {
+	once    sync.Once
+	cleanup func()
+	stop    func() bool
+}
+
+// NewCloser returns a Closer that runs cleanup when ctx is done or Close
+// is called.
+func NewCloser(ctx context.Context, cleanup func()) *Closer {
+	c := &Closer{cleanup: cleanup}
+	// AfterFunc runs c.run on its own goroutine once ctx is done, and
+	// doesn't keep a goroutine parked until then
+	c.stop = context.AfterFunc(ctx, c.run)
+	return c
+}
+
+// Close runs the cleanup if it hasn't run yet, and returns once it has
+// finished, even if it was the context that started it.
+func (c *Closer) Close() {
+	// Stop watching the context, so it doesn't keep a reference to us
+	c.stop()
+	c.run()
+}
+
+func (c *Closer) run() {
+	// `once.Do` also makes a second caller wait until the first call's
+	// cleanup has returned
+	c.once.Do(c.cleanup)
+}
@@ -0,0 +1,52 @@
+package safe
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrentRand hands out random numbers to many goroutines without them
+// all fighting over the single lock inside math/rand's global source.
+//
+// A *rand.Rand on its own is not safe for concurrent use, so each call
+// borrows one from a sync.Pool, uses it, and puts it back. The pool keeps
+// per-P caches, so in the common case a goroutine gets a generator that no
+// other goroutine is touching.
+//
+// Since Go 1.20 the top-level math/rand functions no longer take a lock,
+// and since Go 1.24 rand.Seed does nothing (unless GODEBUG=randseednop=0
+// is set), so they are now faster than this type, and new code should
+// simply use them, or math/rand/v2. ConcurrentRand remains as the fix for
+// code that shares one *rand.Rand between goroutines behind a lock, which
+// does still contend; run ./benchmarks to compare all three.
+type ConcurrentRand struct {
+	pool sync.Pool
+	seq  atomic.Int64
+}
+
+// NewConcurrentRand returns a ConcurrentRand whose generators are seeded
+// from the current time.
+func NewConcurrentRand() *ConcurrentRand {
+	c := &ConcurrentRand{}
+	base := time.Now().UnixNano()
+	c.pool.New = func() any {
+		// Give every generator a different seed, otherwise two of them
+		// created in the same nanosecond would return the same numbers
+		return rand.New(rand.NewSource(base + c.seq.Add(1)))
+	}
+	return c
+}
+
+func (c *ConcurrentRand) Intn(n int) int {
+	r := c.pool.Get().(*rand.Rand)
+	defer c.pool.Put(r)
+	return r.Intn(n)
+}
+
+func (c *ConcurrentRand) Float64() float64 {
+	r := c.pool.Get().(*rand.Rand)
+	defer c.pool.Put(r)
+	return r.Float64()
+}
@@ -0,0 +1,118 @@
+package safe
+
+import (
+	"errors"
+	"runtime/debug"
+	"sync"
+)
+
+// ErrManagerClosed is returned by ConnectionManager.Get after Close.
+var ErrManagerClosed = errors.New("connection manager is closed")
+
+// ConnectionManager opens a connection the first time it is needed and
+// hands the same one to every caller after that.
+//
+// It behaves like sync.Once except that a failed dial is not remembered:
+// callers that were waiting on it all get its error, and the next Get
+// tries again. Concurrent Gets never start more than one dial. A dial
+// that panics counts as failed: the panic carries on in the Get that
+// dialled, and those waiting on it get a *PanicError.
+type ConnectionManager[T any] struct {
+	dial    func() (T, error)
+	closeFn func(T) error
+
+	m       sync.Mutex
+	conn    T
+	ok      bool
+	closed  bool
+	dialing *dialCall[T]
+}
+
+type dialCall[T any] struct {
+	done chan struct{}
+	conn T
+	err  error
+}
+
+// NewConnectionManager returns a manager that connects with dial and
+// disconnects with closeFn, which may be nil.
+func NewConnectionManager[T any](dial func() (T, error), closeFn func(T) error) *ConnectionManager[T] {
+	return &ConnectionManager[T]{dial: dial, closeFn: closeFn}
+}
+
+// Get returns the connection, dialling it first if there isn't one yet.
+func (c *ConnectionManager[T]) Get() (T, error) {
+	c.m.Lock()
+	if c.closed {
+		c.m.Unlock()
+		var zero T
+		return zero, ErrManagerClosed
+	}
+	if c.ok {
+		defer c.m.Unlock()
+		return c.conn, nil
+	}
+	call := c.dialing
+	if call == nil {
+		// We are first: dial ourselves, and let anyone who arrives in
+		// the meantime wait on `call.done` instead of dialling too
+		call = &dialCall[T]{done: make(chan struct{})}
+		c.dialing = call
+		c.m.Unlock()
+		c.doDial(call)
+	} else {
+		c.m.Unlock()
+		<-call.done
+	}
+	return call.conn, call.err
+}
+
+// Close closes the connection, if one was made. Get fails from then on.
+func (c *ConnectionManager[T]) Close() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if !c.ok || c.closeFn == nil {
+		return nil
+	}
+	c.ok = false
+	return c.closeFn(c.conn)
+}
+
+func (c *ConnectionManager[T]) doDial(call *dialCall[T]) {
+	// Without this, a panicking dial would leave `dialing` set and
+	// `done` open, and every Get from then on would wait forever
+	defer func() {
+		if r := recover(); r != nil {
+			c.m.Lock()
+			c.dialing = nil
+			c.m.Unlock()
+			call.err = &PanicError{Value: r, Stack: debug.Stack()}
+			close(call.done)
+			panic(r)
+		}
+	}()
+	// Dialling can take a long time, so it runs without the lock held
+	conn, err := c.dial()
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	defer close(call.done)
+	c.dialing = nil
+	if err == nil && c.closed {
+		// Close ran while we were dialling; don't leak the connection
+		if c.closeFn != nil {
+			c.closeFn(conn)
+		}
+		err = ErrManagerClosed
+	}
+	if err != nil {
+		call.err = err
+		return
+	}
+	c.conn, c.ok = conn, true
+	call.conn = conn
+}
@@ -0,0 +1,57 @@
+package safe
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextValue is a mutex-guarded value, like SafeValue, whose lifetime
+// is tied to a context: when the context is cancelled the value is reset
+// to its zero value and later Sets are ignored. This suits values cached
+// for the length of a single request.
+type ContextValue[T any] struct {
+	m       sync.Mutex
+	val     T
+	expired bool
+	done    chan struct{}
+}
+
+// NewContextValue returns a ContextValue that resets when ctx is done.
+// It starts one goroutine, which exits as soon as ctx is done, so always
+// use a context that does get cancelled at some point.
+func NewContextValue[T any](ctx context.Context) *ContextValue[T] {
+	v := &ContextValue[T]{done: make(chan struct{})}
+	go v.watch(ctx)
+	return v
+}
+
+func (v *ContextValue[T]) Get() T {
+	v.m.Lock()
+	defer v.m.Unlock()
+	return v.val
+}
+
+// Set stores val, unless the context has already been cancelled.
+func (v *ContextValue[T]) Set(val T) {
+	v.m.Lock()
+	defer v.m.Unlock()
+	if !v.expired {
+		v.val = val
+	}
+}
+
+// Done returns a channel that is closed once the value has been reset and
+// the watching goroutine has exited.
+func (v *ContextValue[T]) Done() <-chan struct{} {
+	return v.done
+}
+
+func (v *ContextValue[T]) watch(ctx context.Context) {
+	defer close(v.done)
+	<-ctx.Done()
+	v.m.Lock()
+	defer v.m.Unlock()
+	var zero T
+	v.val = zero
+	v.expired = true
+}
@@ -0,0 +1,55 @@
+package safe
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
+
+// ErrOverflow is returned by SafeCounter.TryAdd when the result would not
+// fit in an int64.
+var ErrOverflow = errors.New("counter overflow")
+
+// SafeCounter is a counter that any number of goroutines can change at
+// once without losing updates.
+//
+// `n++` on a plain int is three steps: read, add, write. Two goroutines
+// can both read 7 and both write 8, and one increment is gone. The atomic
+// Add does all three steps as one, so that can't happen.
+type SafeCounter struct {
+	val atomic.Int64
+}
+
+func (c *SafeCounter) Inc() int64 {
+	return c.val.Add(1)
+}
+
+func (c *SafeCounter) Dec() int64 {
+	return c.val.Add(-1)
+}
+
+// Add adds delta and returns the new value. Like ordinary Go integers,
+// the counter wraps around on overflow; use TryAdd to detect that.
+func (c *SafeCounter) Add(delta int64) int64 {
+	return c.val.Add(delta)
+}
+
+func (c *SafeCounter) Value() int64 {
+	return c.val.Load()
+}
+
+// TryAdd adds delta unless the result would overflow, in which case it
+// leaves the counter unchanged and returns ErrOverflow.
+func (c *SafeCounter) TryAdd(delta int64) (int64, error) {
+	for {
+		old := c.val.Load()
+		if (delta > 0 && old > math.MaxInt64-delta) || (delta < 0 && old < math.MinInt64-delta) {
+			return old, ErrOverflow
+		}
+		// The check above only holds for `old`; if someone changed the
+		// counter since, the CAS fails and we check again
+		if c.val.CompareAndSwap(old, old+delta) {
+			return old + delta, nil
+		}
+	}
+}
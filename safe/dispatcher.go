@@ -0,0 +1,55 @@
+package safe
+
+import "sync"
+
+// Dispatcher runs fire-and-forget tasks in the background while capping
+// how many are running at once. Spawning a goroutine per task with no cap
+// is an easy way to run out of memory under load.
+type Dispatcher struct {
+	// Buffered channel used as a semaphore, one slot per running task
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewDispatcher returns a Dispatcher that runs at most max tasks at once.
+func NewDispatcher(max int) *Dispatcher {
+	if max < 1 {
+		max = 1
+	}
+	return &Dispatcher{slots: make(chan struct{}, max)}
+}
+
+// Dispatch runs fn in a new goroutine, first waiting for a free slot if
+// max tasks are already running.
+func (d *Dispatcher) Dispatch(fn func()) {
+	d.slots <- struct{}{}
+	d.run(fn)
+}
+
+// TryDispatch runs fn in a new goroutine if a slot is free, and returns
+// false without running it otherwise.
+func (d *Dispatcher) TryDispatch(fn func()) bool {
+	select {
+	case d.slots <- struct{}{}:
+		d.run(fn)
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until every task dispatched so far has returned.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) run(fn func()) {
+	// `Add` must happen before the goroutine starts, or a concurrent
+	// Wait could return before this task is counted
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer func() { <-d.slots }()
+		fn()
+	}()
+}
@@ -0,0 +1,5 @@
+// Package safe collects patterns for sharing data between goroutines
+// without data races: blocking with WaitGroups and channels, mutex- and
+// atomic-guarded values, and higher-level building blocks such as pools,
+// pipelines and pub/sub built from them.
+package safe
@@ -0,0 +1,37 @@
+package safe
+
+import "sync"
+
+// DoubleBuffer collects values from many writers while a single consumer
+// processes them in batches.
+//
+// Writers append to the active buffer. Swap trades it for the standby one
+// under the lock, which only takes as long as swapping two slice headers,
+// and returns the filled buffer to be processed with no lock held. The two
+// buffers take turns, so after warming up no new memory is allocated.
+type DoubleBuffer[T any] struct {
+	m       sync.Mutex
+	active  []T
+	standby []T
+}
+
+func (d *DoubleBuffer[T]) Append(v T) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.active = append(d.active, v)
+}
+
+// Swap returns everything appended since the previous Swap. The returned
+// slice is reused as the active buffer on the next call, so it is only
+// valid until then, and Swap must not be called from more than one
+// goroutine at a time.
+func (d *DoubleBuffer[T]) Swap() []T {
+	d.m.Lock()
+	defer d.m.Unlock()
+	full := d.active
+	// Reuse the standby buffer's memory, which the caller of the
+	// previous Swap has finished with by now
+	d.active = d.standby[:0]
+	d.standby = full
+	return full
+}
@@ -0,0 +1,23 @@
+package safe
+
+import "time"
+
+// DrainWithTimeout reads from ch until it is closed or d has passed,
+// whichever comes first. It returns everything it read, and true only if
+// the channel was closed before the timeout.
+func DrainWithTimeout[T any](ch <-chan T, d time.Duration) ([]T, bool) {
+	var got []T
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return got, true
+			}
+			got = append(got, v)
+		case <-timer.C:
+			return got, false
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package safe
+
+import (
+	"context"
+	"sync"
+)
+
+// FetchFunc models a single I/O operation, such as downloading one URL.
+// It should give up as soon as ctx is cancelled.
+type FetchFunc[T any] func(ctx context.Context) (T, error)
+
+// FetchAll runs fetchers with at most limit of them in flight at once and
+// returns their results in input order. The first failure cancels the
+// context handed to every other fetcher, no further fetchers are started,
+// and that error is returned once all started fetchers have come back.
+func FetchAll[T any](ctx context.Context, limit int, fetchers []FetchFunc[T]) ([]T, error) {
+	if limit < 1 {
+		limit = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]T, len(fetchers))
+	// A buffered channel doubles as a counting semaphore: sending takes a
+	// slot, and blocks while all `limit` slots are taken
+	sem := make(chan struct{}, limit)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for i, fetch := range fetchers {
+		// Stop handing out work once something has failed
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		wg.Add(1)
+		go func(i int, fetch FetchFunc[T]) {
+			defer wg.Done()
+			// Give the slot back so the next fetcher can start
+			defer func() { <-sem }()
+			v, err := fetch(ctx)
+			if err != nil {
+				// Only the first failure is recorded, and it tells
+				// everyone else to stop
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			// Each goroutine owns its own index, so no lock is needed
+			results[i] = v
+		}(i, fetch)
+	}
+	// Every write to `results` and `firstErr` happens before `wg.Done`,
+	// so it is safe to read them once `wg.Wait` returns
+	wg.Wait()
+	if firstErr != nil {
+		return results, firstErr
+	}
+	return results, ctx.Err()
+}
@@ -0,0 +1,54 @@
+package safe
+
+import (
+	"context"
+	"runtime/debug"
+)
+
+// Future is the result of a function running in the background. It is
+// what ReturningWithChannel grows into once a bare channel is not
+// enough: it carries an error as well as a value, and any number of
+// goroutines can wait for it, any number of times.
+type Future[T any] struct {
+	done chan struct{}
+	// `val` and `err` are written once, before `done` is closed, and
+	// only read after, so they need no lock
+	val T
+	err error
+}
+
+// Go runs fn in a new goroutine and returns a Future for its result. If
+// fn panics, the panic is recovered and the Future fails with a
+// *PanicError.
+func Go[T any](fn func() (T, error)) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		defer func() {
+			if r := recover(); r != nil {
+				f.err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		f.val, f.err = fn()
+	}()
+	return f
+}
+
+// Await waits for the function to finish and returns its result, or
+// returns ctx.Err() if ctx is done first. Giving up does not stop the
+// function; the Future can still be awaited again later.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed once the result is ready, for use
+// in a select.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
@@ -0,0 +1,52 @@
+package safe
+
+import "sync"
+
+// ScatterGather runs every function in fns in its own goroutine and
+// returns their results, with results[i] coming from fns[i].
+func ScatterGather[T any](fns []func() T) []T {
+	// Allocating the whole slice up front means each goroutine writes
+	// to its own element. Nothing is appended, and no two goroutines
+	// touch the same memory, so no lock is needed
+	results := make([]T, len(fns))
+	var wg sync.WaitGroup
+	wg.Add(len(fns))
+	for i, fn := range fns {
+		go func(i int, fn func() T) {
+			defer wg.Done()
+			results[i] = fn()
+		}(i, fn)
+	}
+	// `wg.Wait` makes every write above visible to us before we return
+	wg.Wait()
+	return results
+}
+
+// GatherWithError runs every function in fns in its own goroutine and
+// waits for all of them. results[i] comes from fns[i], and is left as the
+// zero value if fns[i] failed. The returned error is the first one to
+// occur, by the time it happened, or nil if none did.
+func GatherWithError[T any](fns []func() (T, error)) ([]T, error) {
+	results := make([]T, len(fns))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	wg.Add(len(fns))
+	for i, fn := range fns {
+		go func(i int, fn func() (T, error)) {
+			defer wg.Done()
+			v, err := fn()
+			if err != nil {
+				// Whoever gets here first fills the slot; later errors
+				// are dropped
+				once.Do(func() { firstErr = err })
+				return
+			}
+			results[i] = v
+		}(i, fn)
+	}
+	wg.Wait()
+	return results, firstErr
+}
@@ -0,0 +1,55 @@
+package safe
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// Histogram counts observations into buckets. The bucket boundaries are
+// fixed when it is created, so Observe only has to find the bucket and
+// bump an atomic counter, without taking any lock.
+type Histogram struct {
+	bounds []float64
+	// counts[i] holds observations v with bounds[i-1] < v <= bounds[i];
+	// the extra last bucket holds everything above the highest bound
+	counts []atomic.Uint64
+}
+
+// HistogramSnapshot is a copy of a Histogram's counts. Counts has one
+// more entry than Bounds, for values above the last bound.
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64
+}
+
+// NewHistogram returns a Histogram with the given upper bucket bounds,
+// which may be passed in any order.
+func NewHistogram(bounds ...float64) *Histogram {
+	b := append([]float64(nil), bounds...)
+	sort.Float64s(b)
+	return &Histogram{
+		bounds: b,
+		counts: make([]atomic.Uint64, len(b)+1),
+	}
+}
+
+func (h *Histogram) Observe(v float64) {
+	// `bounds` is never written after construction, so it can be read
+	// from any goroutine without synchronization
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i].Add(1)
+}
+
+// Snapshot returns the current count of every bucket. As with Stats.Load,
+// the buckets are read one at a time, so observations made during the
+// call may show up in some buckets and not others.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Bounds: append([]float64(nil), h.bounds...),
+		Counts: make([]uint64, len(h.counts)),
+	}
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+	}
+	return s
+}
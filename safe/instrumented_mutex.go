@@ -0,0 +1,41 @@
+package safe
+
+import (
+	"sync"
+	"time"
+)
+
+// InstrumentedMutex is a sync.Mutex that reports when it has been held for
+// too long, to help find the critical sections that cause contention.
+// The cost over a plain mutex is one clock read on Lock and one on Unlock.
+type InstrumentedMutex struct {
+	m         sync.Mutex
+	threshold time.Duration
+	onSlow    func(held time.Duration)
+
+	// `acquired` is only read and written by whoever holds `m`, so it
+	// needs no protection of its own
+	acquired time.Time
+}
+
+// NewInstrumentedMutex returns an unlocked mutex that calls onSlow with
+// the hold time whenever it is held for longer than threshold.
+func NewInstrumentedMutex(threshold time.Duration, onSlow func(held time.Duration)) *InstrumentedMutex {
+	return &InstrumentedMutex{threshold: threshold, onSlow: onSlow}
+}
+
+func (i *InstrumentedMutex) Lock() {
+	i.m.Lock()
+	i.acquired = time.Now()
+}
+
+// Unlock releases the mutex, then calls onSlow if it was held too long.
+// The callback runs after unlocking so a slow callback doesn't make the
+// problem it is reporting worse.
+func (i *InstrumentedMutex) Unlock() {
+	held := time.Since(i.acquired)
+	i.m.Unlock()
+	if held > i.threshold && i.onSlow != nil {
+		i.onSlow(held)
+	}
+}
@@ -0,0 +1,39 @@
+package safe
+
+import "sync"
+
+// Latch blocks goroutines in Wait until Signal is called. After that,
+// every Wait, including ones that start later, returns immediately.
+//
+// The zero value is not usable; create one with NewLatch.
+type Latch struct {
+	ch   chan struct{}
+	once sync.Once
+}
+
+// NewLatch returns a Latch that has not been signalled yet.
+func NewLatch() *Latch {
+	return &Latch{ch: make(chan struct{})}
+}
+
+// Wait blocks until Signal has been called.
+func (l *Latch) Wait() {
+	// Receiving from a closed channel never blocks, which is what lets
+	// one close wake up any number of waiters
+	<-l.ch
+}
+
+// Done returns a channel that is closed once Signal has been called, for
+// use in a select.
+func (l *Latch) Done() <-chan struct{} {
+	return l.ch
+}
+
+// Signal releases all waiters. Calling it more than once is harmless.
+func (l *Latch) Signal() {
+	// Closing a channel twice panics, so `sync.Once` makes sure it only
+	// ever happens once
+	l.once.Do(func() {
+		close(l.ch)
+	})
+}
@@ -0,0 +1,33 @@
+package safe
+
+import "sync"
+
+// Lazy holds a value that is only computed the first time it's needed.
+//
+// Checking `if v == nil { v = init() }` from many goroutines races: several
+// of them can see nil and all run init. sync.Once lets exactly one of them
+// in and makes the rest wait until it's done. The standard library's
+// sync.OnceValue does the same as a function; Lazy is the same idea as a
+// type, to show what is underneath.
+type Lazy[T any] struct {
+	once sync.Once
+	init func() T
+	val  T
+}
+
+// NewLazy returns a Lazy that calls init on the first Get.
+func NewLazy[T any](init func() T) *Lazy[T] {
+	return &Lazy[T]{init: init}
+}
+
+// Get returns the value, computing it first if no one has yet. If init
+// panics, the panic goes to the caller whose Get ran it, and later calls
+// return the zero value: sync.Once counts a panicked call as done.
+func (l *Lazy[T]) Get() T {
+	l.once.Do(func() {
+		l.val = l.init()
+		// Let the closure and whatever it holds on to be collected
+		l.init = nil
+	})
+	return l.val
+}
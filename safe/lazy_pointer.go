@@ -0,0 +1,45 @@
+package safe
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// LazyPointer is double-checked locking done right: a value built on
+// first use, with later calls costing only an atomic load.
+//
+// Lazy does the same with sync.Once, which is simpler and what to reach
+// for first. LazyPointer shows what makes the hand-written version work:
+// the pointer is atomic, so reading it without the lock is not a race,
+// and it is only stored once the value is completely filled in, so
+// whoever loads it sees every field.
+type LazyPointer[T any] struct {
+	m    sync.Mutex
+	p    atomic.Pointer[T]
+	init func(*T)
+}
+
+// NewLazyPointer returns a LazyPointer that fills in a new T with init on
+// the first Get.
+func NewLazyPointer[T any](init func(*T)) *LazyPointer[T] {
+	return &LazyPointer[T]{init: init}
+}
+
+func (l *LazyPointer[T]) Get() *T {
+	// The fast path: once the value exists, no lock is taken
+	if p := l.p.Load(); p != nil {
+		return p
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	// Another goroutine may have built it while we waited for the lock
+	if p := l.p.Load(); p != nil {
+		return p
+	}
+	p := new(T)
+	l.init(p)
+	// Fill in first, publish after: the Store makes everything init
+	// wrote visible to any goroutine whose Load sees p
+	l.p.Store(p)
+	return p
+}
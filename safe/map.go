@@ -0,0 +1,132 @@
+package safe
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// defaultMapShards is used by NewSafeMap when no shard count is given
+const defaultMapShards = 32
+
+// SafeMap is a map that many goroutines can use at once.
+//
+// A single mutex around a map makes every goroutine queue on the same
+// lock. SafeMap instead splits its keys over several shards, each a plain
+// map with its own RWMutex, so goroutines working on different keys
+// rarely wait for each other. Whether that beats sync.Map depends on the
+// load: sync.Map is built for keys that are written once and read many
+// times, while sharding does better when keys are updated often. Run
+// ./benchmarks to compare them.
+type SafeMap[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []mapShard[K, V]
+}
+
+type mapShard[K comparable, V any] struct {
+	m    sync.RWMutex
+	vals map[K]V
+}
+
+// NewSafeMap returns an empty map split into at least shards shards, or
+// a default number if shards is not positive.
+func NewSafeMap[K comparable, V any](shards int) *SafeMap[K, V] {
+	if shards < 1 {
+		shards = defaultMapShards
+	}
+	m := &SafeMap[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]mapShard[K, V], shards),
+	}
+	for i := range m.shards {
+		m.shards[i].vals = make(map[K]V)
+	}
+	return m
+}
+
+func (m *SafeMap[K, V]) shard(k K) *mapShard[K, V] {
+	h := maphash.Comparable(m.seed, k)
+	return &m.shards[h%uint64(len(m.shards))]
+}
+
+func (m *SafeMap[K, V]) Get(k K) (V, bool) {
+	s := m.shard(k)
+	s.m.RLock()
+	defer s.m.RUnlock()
+	v, ok := s.vals[k]
+	return v, ok
+}
+
+func (m *SafeMap[K, V]) Set(k K, v V) {
+	s := m.shard(k)
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.vals[k] = v
+}
+
+func (m *SafeMap[K, V]) Delete(k K) {
+	s := m.shard(k)
+	s.m.Lock()
+	defer s.m.Unlock()
+	delete(s.vals, k)
+}
+
+// GetOrCompute returns the value for k, first storing compute() under k
+// if there is no value yet. compute runs at most once per missing key,
+// however many goroutines ask for it at once. It runs with k's shard
+// locked, so it must not use the map itself.
+func (m *SafeMap[K, V]) GetOrCompute(k K, compute func() V) V {
+	s := m.shard(k)
+	// Most calls find the key already there, so try with the shared
+	// lock first
+	s.m.RLock()
+	v, ok := s.vals[k]
+	s.m.RUnlock()
+	if ok {
+		return v
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	// Another goroutine may have stored it between our two locks
+	if v, ok := s.vals[k]; ok {
+		return v
+	}
+	v = compute()
+	s.vals[k] = v
+	return v
+}
+
+// Len returns the number of keys. Shards are counted one at a time, so
+// with concurrent writes the result may not match the map at any single
+// moment.
+func (m *SafeMap[K, V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.m.RLock()
+		n += len(s.vals)
+		s.m.RUnlock()
+	}
+	return n
+}
+
+// Range calls fn for every key and value until fn returns false. Each
+// shard is copied before fn sees it, so fn may use the map freely, but
+// changes made during Range may or may not be visited.
+func (m *SafeMap[K, V]) Range(fn func(k K, v V) bool) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.m.RLock()
+		keys := make([]K, 0, len(s.vals))
+		vals := make([]V, 0, len(s.vals))
+		for k, v := range s.vals {
+			keys = append(keys, k)
+			vals = append(vals, v)
+		}
+		s.m.RUnlock()
+		for j := range keys {
+			if !fn(keys[j], vals[j]) {
+				return
+			}
+		}
+	}
+}
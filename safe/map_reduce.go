@@ -0,0 +1,51 @@
+package safe
+
+import "sync"
+
+// MapReduceByKey groups items by keyFn and folds each group into a single
+// value with reduceFn, using up to workers goroutines.
+//
+// Items are combined in no particular order, so reduceFn should be
+// associative and commutative (a sum, a max, ...), or the result will
+// change from run to run.
+func MapReduceByKey[T any, K comparable](items []T, workers int, keyFn func(T) K, reduceFn func(existing, new T) T) map[K]T {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		m      sync.Mutex
+		result = make(map[K]T)
+		wg     sync.WaitGroup
+	)
+	work := make(chan T)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each worker reduces into its own map first, so the shared
+			// map is only locked once per worker instead of per item
+			local := make(map[K]T)
+			for item := range work {
+				reduceInto(local, keyFn(item), item, reduceFn)
+			}
+			m.Lock()
+			defer m.Unlock()
+			for k, v := range local {
+				reduceInto(result, k, v, reduceFn)
+			}
+		}()
+	}
+	for _, item := range items {
+		work <- item
+	}
+	close(work)
+	wg.Wait()
+	return result
+}
+
+func reduceInto[T any, K comparable](dst map[K]T, k K, v T, reduceFn func(existing, new T) T) {
+	if existing, ok := dst[k]; ok {
+		v = reduceFn(existing, v)
+	}
+	dst[k] = v
+}
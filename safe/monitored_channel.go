@@ -0,0 +1,47 @@
+package safe
+
+import "sync/atomic"
+
+// MonitoredChannel is a buffered channel that remembers the most values
+// it has held at once. A high-water mark close to the capacity means
+// the readers can't keep up and senders are being held back.
+type MonitoredChannel[T any] struct {
+	ch        chan T
+	highWater atomic.Int64
+}
+
+// NewMonitoredChannel returns a channel with room for capacity values.
+func NewMonitoredChannel[T any](capacity int) *MonitoredChannel[T] {
+	return &MonitoredChannel[T]{ch: make(chan T, capacity)}
+}
+
+// Send sends v, blocking while the channel is full, and then updates the
+// high-water mark.
+func (c *MonitoredChannel[T]) Send(v T) {
+	c.ch <- v
+	// len can already be stale by the time we read it, since readers
+	// keep going, so the mark is a close estimate rather than exact
+	n := int64(len(c.ch))
+	for {
+		old := c.highWater.Load()
+		if n <= old || c.highWater.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+// Chan returns the channel to receive from.
+func (c *MonitoredChannel[T]) Chan() <-chan T {
+	return c.ch
+}
+
+// Close closes the channel. Send must not be called afterwards.
+func (c *MonitoredChannel[T]) Close() {
+	close(c.ch)
+}
+
+// Stats returns how many values are in the channel now, how many it can
+// hold, and the most it has held at once.
+func (c *MonitoredChannel[T]) Stats() (length, capacity, highWater int) {
+	return len(c.ch), cap(c.ch), int(c.highWater.Load())
+}
@@ -0,0 +1,15 @@
+package safe
+
+import "sync"
+
+// Mutexed wraps fn so that at most one call to it runs at a time, no
+// matter how many goroutines call the returned function. Callers queue up
+// on a mutex and run in turn.
+func Mutexed[T, R any](fn func(T) R) func(T) R {
+	var m sync.Mutex
+	return func(arg T) R {
+		m.Lock()
+		defer m.Unlock()
+		return fn(arg)
+	}
+}
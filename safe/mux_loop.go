@@ -0,0 +1,120 @@
+package safe
+
+import (
+	"reflect"
+	"sync"
+)
+
+// MuxLoop runs one goroutine that selects over a set of channels which can
+// grow while it is running, and calls each channel's handler with the
+// values it receives.
+//
+// A select statement needs its cases written out at compile time, so this
+// uses reflect.Select instead. That is several times slower than a plain
+// select and boxes every value in a reflect.Value, so it is best kept for
+// control-plane traffic rather than hot data paths.
+type MuxLoop struct {
+	// MuxRegister doesn't hand new channels to the loop directly, since
+	// a handler registering one would then be waiting for itself. It
+	// queues them in `pending` and nudges `wake`, which has room for one
+	// nudge so sending never blocks, and the loop picks them up between
+	// selects
+	m       sync.Mutex
+	pending []muxEntry
+	stopped bool
+	wake    chan struct{}
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+type muxEntry struct {
+	ch reflect.Value
+	fn func(reflect.Value)
+}
+
+// NewMuxLoop starts a MuxLoop with no channels registered.
+func NewMuxLoop() *MuxLoop {
+	l := &MuxLoop{
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// MuxRegister adds ch to the loop, so that fn is called on the loop's
+// goroutine with every value received from it. Handlers never run at the
+// same time as each other. Once ch is closed it is dropped from the loop.
+// It may be called from a handler. Registering on a stopped loop does
+// nothing.
+func MuxRegister[T any](l *MuxLoop, ch <-chan T, fn func(T)) {
+	e := muxEntry{
+		ch: reflect.ValueOf(ch),
+		fn: func(v reflect.Value) { fn(v.Interface().(T)) },
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.stopped {
+		return
+	}
+	l.pending = append(l.pending, e)
+	select {
+	case l.wake <- struct{}{}:
+	default:
+		// A nudge is already waiting, and the loop will pick up
+		// everything pending when it takes it
+	}
+}
+
+// Stop ends the loop and waits for the handler that is running, if any,
+// to return. It must not be called from a handler, which would then be
+// waiting for itself to return.
+func (l *MuxLoop) Stop() {
+	l.once.Do(func() { close(l.stop) })
+	<-l.done
+}
+
+func (l *MuxLoop) run() {
+	defer close(l.done)
+	defer func() {
+		l.m.Lock()
+		l.stopped = true
+		l.pending = nil
+		l.m.Unlock()
+	}()
+	// The first two cases are always the loop's own control channels;
+	// registered channels and their handlers follow, kept at the same
+	// positions in `cases` and `handlers` (offset by two)
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(l.wake)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(l.stop)},
+	}
+	var handlers []func(reflect.Value)
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			l.m.Lock()
+			for _, e := range l.pending {
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: e.ch})
+				handlers = append(handlers, e.fn)
+			}
+			l.pending = nil
+			l.m.Unlock()
+		case 1:
+			return
+		default:
+			if !ok {
+				// A closed channel is always ready, so leaving it in
+				// would turn the loop into a busy spin
+				cases = append(cases[:chosen], cases[chosen+1:]...)
+				handlers = append(handlers[:chosen-2], handlers[chosen-1:]...)
+				continue
+			}
+			handlers[chosen-2](v)
+		}
+	}
+}
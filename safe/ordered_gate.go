@@ -0,0 +1,71 @@
+package safe
+
+import "sync"
+
+// OrderedGate puts results from parallel workers back in order. Workers
+// Emit values tagged with sequence numbers starting at 0, in any order,
+// and Out delivers them strictly in sequence: value n+1 is held back
+// until value n has arrived, however long that takes.
+type OrderedGate[T any] struct {
+	in   chan sequenced[T]
+	out  chan T
+	once sync.Once
+}
+
+type sequenced[T any] struct {
+	seq int
+	val T
+}
+
+// NewOrderedGate starts a gate waiting for sequence number 0.
+func NewOrderedGate[T any]() *OrderedGate[T] {
+	g := &OrderedGate[T]{
+		in:  make(chan sequenced[T]),
+		out: make(chan T),
+	}
+	go g.run()
+	return g
+}
+
+// Emit hands in the value for sequence number seq. Each number should be
+// emitted once; repeats of a number that has already been emitted are
+// dropped. Emit must not be called after Close.
+func (g *OrderedGate[T]) Emit(seq int, v T) {
+	g.in <- sequenced[T]{seq: seq, val: v}
+}
+
+// Out returns the channel the values come out of, in sequence order.
+func (g *OrderedGate[T]) Out() <-chan T {
+	return g.out
+}
+
+// Close tells the gate no more values are coming. Out is closed once
+// everything that can be released has been; values stuck behind a
+// missing sequence number are discarded.
+func (g *OrderedGate[T]) Close() {
+	g.once.Do(func() { close(g.in) })
+}
+
+func (g *OrderedGate[T]) run() {
+	defer close(g.out)
+	// Only this goroutine touches `next` and `pending`, so they need no
+	// lock, and a slow reader of Out holds up only this goroutine rather
+	// than every Emit sitting on a mutex
+	next := 0
+	pending := make(map[int]T)
+	for s := range g.in {
+		if _, dup := pending[s.seq]; dup || s.seq < next {
+			continue
+		}
+		pending[s.seq] = s.val
+		for {
+			v, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			g.out <- v
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package safe
+
+import "sync"
+
+// ParallelFor calls fn(0), fn(1), ..., fn(n-1) with at most workers calls
+// running at once, and returns when they have all finished. The calls
+// happen in no particular order, so fn must not depend on one index
+// being handled before another.
+func ParallelFor(n, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	// Buffered channel used as a semaphore: a goroutine holds one of
+	// the `workers` slots while it runs
+	sem := make(chan struct{}, workers)
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,147 @@
+package safe
+
+import "container/heap"
+
+// Filter forwards the values from in for which pred returns true, and
+// closes the returned channel once in is closed.
+func Filter[T any](in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		// Closing `out` when we are done lets the next stage `range`
+		// over it
+		defer close(out)
+		for v := range in {
+			if pred(v) {
+				out <- v
+			}
+		}
+	}()
+	return out
+}
+
+// Pair holds one value from each of two channels.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Zip pairs up the values of a and b by position: the first value of a
+// with the first value of b, and so on. The returned channel is closed as
+// soon as either input is closed, even while Zip is waiting on the other
+// one, so with inputs of different lengths the output stops at the
+// shorter one, and a value left without a partner is dropped. The extra
+// values of the longer input are left unread, so whoever sends them must
+// not block forever on a Zip that has finished.
+func Zip[A, B any](a <-chan A, b <-chan B) <-chan Pair[A, B] {
+	out := make(chan Pair[A, B])
+	go func() {
+		defer close(out)
+		for {
+			var (
+				p            Pair[A, B]
+				haveA, haveB bool
+			)
+			// Wait on both at once, so a close is noticed whichever input
+			// it comes from. Once we have one side's value, its channel is
+			// set to nil, since receiving from a nil channel blocks forever
+			// and takes that case out of the select
+			ca, cb := a, b
+			for !haveA || !haveB {
+				var ok bool
+				select {
+				case p.First, ok = <-ca:
+					haveA, ca = true, nil
+				case p.Second, ok = <-cb:
+					haveB, cb = true, nil
+				}
+				if !ok {
+					return
+				}
+			}
+			out <- p
+		}
+	}()
+	return out
+}
+
+// DistinctUntilChanged forwards the values from in, skipping any value
+// equal to the one forwarded just before it. The first value is always
+// forwarded. The returned channel is closed once in is closed.
+func DistinctUntilChanged[T comparable](in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var prev T
+		first := true
+		for v := range in {
+			if !first && v == prev {
+				continue
+			}
+			first = false
+			prev = v
+			out <- v
+		}
+	}()
+	return out
+}
+
+// MergeSorted merges channels that each deliver values in ascending order
+// (according to less) into one channel that delivers all of them in
+// ascending order. The returned channel is closed once every input has
+// been closed and drained. Duplicates are kept; chain the output through
+// DistinctUntilChanged to drop them.
+//
+// It keeps a min-heap holding the next value of each input, so every
+// output value costs O(log k) for k inputs.
+func MergeSorted[T any](less func(a, b T) bool, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		h := &mergeHeap[T]{less: less}
+		// Prime the heap with the first value of every input; an input
+		// that is closed without sending anything just drops out
+		for _, ch := range chans {
+			if v, ok := <-ch; ok {
+				h.items = append(h.items, mergeItem[T]{val: v, src: ch})
+			}
+		}
+		heap.Init(h)
+		for h.Len() > 0 {
+			// The smallest head goes out, and is replaced by the next
+			// value from the same input, if there is one
+			top := h.items[0]
+			out <- top.val
+			if v, ok := <-top.src; ok {
+				h.items[0].val = v
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+		}
+	}()
+	return out
+}
+
+type mergeItem[T any] struct {
+	val T
+	src <-chan T
+}
+
+// mergeHeap implements heap.Interface over the current head of each
+// input channel
+type mergeHeap[T any] struct {
+	items []mergeItem[T]
+	less  func(a, b T) bool
+}
+
+func (h *mergeHeap[T]) Len() int           { return len(h.items) }
+func (h *mergeHeap[T]) Less(i, j int) bool { return h.less(h.items[i].val, h.items[j].val) }
+func (h *mergeHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *mergeHeap[T]) Push(x any)         { h.items = append(h.items, x.(mergeItem[T])) }
+
+func (h *mergeHeap[T]) Pop() any {
+	n := len(h.items)
+	x := h.items[n-1]
+	h.items = h.items[:n-1]
+	return x
+}
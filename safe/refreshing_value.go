@@ -0,0 +1,84 @@
+package safe
+
+import (
+	"sync"
+	"time"
+)
+
+// RefreshingValue caches the result of an expensive loader for a while and
+// loads it again once it has expired.
+//
+// However many goroutines ask for an expired value at the same moment,
+// the loader runs only once and they all share its result; without this,
+// every caller would hit the backing service at once (a "cache
+// stampede"). With serveStale set, callers don't wait for the refresh at
+// all and get the expired value until the new one is in.
+type RefreshingValue[T any] struct {
+	load       func() (T, error)
+	ttl        time.Duration
+	serveStale bool
+
+	m        sync.Mutex
+	val      T
+	loaded   bool
+	loadedAt time.Time
+	// `inflight` is non-nil while a refresh is running
+	inflight *refreshCall[T]
+}
+
+type refreshCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// NewRefreshingValue returns a RefreshingValue that calls load on first
+// use and whenever the last successful load is older than ttl.
+func NewRefreshingValue[T any](ttl time.Duration, serveStale bool, load func() (T, error)) *RefreshingValue[T] {
+	return &RefreshingValue[T]{load: load, ttl: ttl, serveStale: serveStale}
+}
+
+// Get returns the cached value if it is fresh. Otherwise it starts a
+// refresh, or joins the one already running, and returns its result; or,
+// with serveStale, returns the previous value straight away. A failed
+// refresh leaves the previous value in place and returns the error to
+// those who waited for it.
+func (r *RefreshingValue[T]) Get() (T, error) {
+	r.m.Lock()
+	if r.loaded && time.Since(r.loadedAt) < r.ttl {
+		defer r.m.Unlock()
+		return r.val, nil
+	}
+	call := r.inflight
+	if call == nil {
+		call = &refreshCall[T]{done: make(chan struct{})}
+		r.inflight = call
+		go r.refresh(call)
+	}
+	if r.serveStale && r.loaded {
+		defer r.m.Unlock()
+		return r.val, nil
+	}
+	r.m.Unlock()
+
+	// Everything written to `call` happens before `done` is closed, so
+	// it is safe to read once this receive returns
+	<-call.done
+	return call.val, call.err
+}
+
+func (r *RefreshingValue[T]) refresh(call *refreshCall[T]) {
+	// The loader runs without the lock held, so fresh-value readers and
+	// stale-serving readers are never stuck behind it
+	call.val, call.err = r.load()
+
+	r.m.Lock()
+	if call.err == nil {
+		r.val = call.val
+		r.loaded = true
+		r.loadedAt = time.Now()
+	}
+	r.inflight = nil
+	r.m.Unlock()
+	close(call.done)
+}
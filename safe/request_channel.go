@@ -0,0 +1,57 @@
+package safe
+
+import "context"
+
+// RequestChannel carries requests from any number of clients to a server
+// goroutine and the responses back, using nothing but channels.
+//
+// It is the two-way version of ReturningWithChannel: each request
+// travels with its own reply channel, so every client gets back the
+// answer to its own question.
+type RequestChannel[Req, Resp any] struct {
+	reqs chan envelope[Req, Resp]
+}
+
+type envelope[Req, Resp any] struct {
+	req   Req
+	reply chan Resp
+}
+
+// NewRequestChannel returns a RequestChannel with no server yet; requests
+// wait until a goroutine calls Serve.
+func NewRequestChannel[Req, Resp any]() *RequestChannel[Req, Resp] {
+	return &RequestChannel[Req, Resp]{reqs: make(chan envelope[Req, Resp])}
+}
+
+// Do sends req to the server and waits for its response. It returns
+// ctx.Err() if ctx is done before the server has replied.
+func (c *RequestChannel[Req, Resp]) Do(ctx context.Context, req Req) (Resp, error) {
+	// The reply channel has room for one value, so the server can always
+	// drop its response off and move on, even if we have given up
+	e := envelope[Req, Resp]{req: req, reply: make(chan Resp, 1)}
+	var zero Resp
+	select {
+	case c.reqs <- e:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+	select {
+	case resp := <-e.reply:
+		return resp, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Serve answers requests with handler, one at a time, until ctx is done.
+// Several goroutines may call Serve on the same channel to share the load.
+func (c *RequestChannel[Req, Resp]) Serve(ctx context.Context, handler func(Req) Resp) error {
+	for {
+		select {
+		case e := <-c.reqs:
+			e.reply <- handler(e.req)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package safe
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ResizablePool is a worker pool whose number of workers can be changed
+// while it is running.
+type ResizablePool struct {
+	jobs chan func()
+
+	// `mu` guards `quits`, which holds one quit channel per worker that
+	// has not been asked to stop yet
+	mu    sync.Mutex
+	quits []chan struct{}
+
+	running atomic.Int64
+	wg      sync.WaitGroup
+}
+
+// NewResizablePool starts a pool with n workers.
+func NewResizablePool(n int) *ResizablePool {
+	p := &ResizablePool{jobs: make(chan func())}
+	p.Resize(n)
+	return p
+}
+
+// Submit blocks until a worker has picked up job. With zero workers it
+// blocks until the pool is grown again.
+func (p *ResizablePool) Submit(job func()) {
+	p.jobs <- job
+}
+
+// Resize grows or shrinks the pool to n workers. New workers start right
+// away; workers being removed finish the job they are running, if any,
+// and then exit.
+func (p *ResizablePool) Resize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.quits) < n {
+		quit := make(chan struct{})
+		p.quits = append(p.quits, quit)
+		p.running.Add(1)
+		p.wg.Add(1)
+		go p.worker(quit)
+	}
+	for len(p.quits) > n {
+		last := len(p.quits) - 1
+		// Closing the channel is how we tell that one worker to stop
+		close(p.quits[last])
+		p.quits = p.quits[:last]
+	}
+}
+
+// Size returns the number of workers the pool is aiming for.
+func (p *ResizablePool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.quits)
+}
+
+// Running returns the number of worker goroutines still alive. After a
+// shrink it lags behind Size until the removed workers finish their jobs.
+func (p *ResizablePool) Running() int {
+	return int(p.running.Load())
+}
+
+// Close stops every worker and waits for them to exit. Submit must not be
+// called after Close.
+func (p *ResizablePool) Close() {
+	p.Resize(0)
+	p.wg.Wait()
+}
+
+func (p *ResizablePool) worker(quit <-chan struct{}) {
+	defer p.wg.Done()
+	defer p.running.Add(-1)
+	for {
+		// A worker only checks for `quit` between jobs, so a job that
+		// has been handed over always runs to completion
+		select {
+		case <-quit:
+			return
+		case job := <-p.jobs:
+			job()
+		}
+	}
+}
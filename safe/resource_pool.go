@@ -0,0 +1,157 @@
+package safe
+
+import (
+	"sync"
+	"time"
+)
+
+// ResourcePool keeps a bounded set of expensive resources, such as
+// database connections, for reuse.
+//
+// Unlike sync.Pool, which may drop anything it holds on any GC, a
+// ResourcePool never creates more than max resources at once, makes Get
+// wait while they are all in use, and only throws a resource away once it
+// has sat unused for longer than ttl.
+type ResourcePool[T any] struct {
+	newFn   func() (T, error)
+	closeFn func(T)
+	max     int
+	ttl     time.Duration
+
+	// `mu` guards everything below, and `cond` is signalled whenever a
+	// resource is returned or a slot frees up
+	mu   sync.Mutex
+	cond *sync.Cond
+	live int
+	// `free` is ordered by the time each resource was returned, oldest
+	// first, so expired entries are always at the front
+	free []idleResource[T]
+}
+
+type idleResource[T any] struct {
+	val   T
+	since time.Time
+}
+
+// NewResourcePool returns a pool that creates resources with newFn, allows
+// at most max of them to exist at once, and closes idle ones with closeFn
+// after ttl. A ttl of zero or less keeps idle resources forever. closeFn
+// may be nil.
+func NewResourcePool[T any](max int, ttl time.Duration, newFn func() (T, error), closeFn func(T)) *ResourcePool[T] {
+	if max < 1 {
+		max = 1
+	}
+	p := &ResourcePool[T]{
+		newFn:   newFn,
+		closeFn: closeFn,
+		max:     max,
+		ttl:     ttl,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Get returns an idle resource if there is one, creates a new one if the
+// pool is below its cap, and otherwise blocks until one is Put back.
+func (p *ResourcePool[T]) Get() (T, error) {
+	// Close what has expired before we might have to wait, rather than
+	// after, so a long wait doesn't hold up closeFn
+	p.EvictIdle()
+	p.mu.Lock()
+	for len(p.free) == 0 && p.live >= p.max {
+		// `Wait` unlocks `mu` while sleeping and locks it again before
+		// returning, so the condition has to be checked again in a loop
+		p.cond.Wait()
+	}
+	if n := len(p.free); n > 0 {
+		// Reuse the most recently returned resource, which lets the
+		// older ones age out if the pool is bigger than it needs to be
+		r := p.free[n-1]
+		p.free = p.free[:n-1]
+		p.mu.Unlock()
+		return r.val, nil
+	}
+	// Reserve the slot before unlocking, so other callers can't race
+	// past the cap while we are busy creating the resource
+	p.live++
+	p.mu.Unlock()
+
+	val, err := p.newFn()
+	if err != nil {
+		p.mu.Lock()
+		p.live--
+		p.mu.Unlock()
+		p.cond.Signal()
+	}
+	return val, err
+}
+
+// Put returns a resource obtained from Get to the pool.
+func (p *ResourcePool[T]) Put(val T) {
+	p.mu.Lock()
+	p.free = append(p.free, idleResource[T]{val: val, since: time.Now()})
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+// Discard tells the pool that a resource obtained from Get is broken and
+// will not be returned, which frees its slot for a new one.
+func (p *ResourcePool[T]) Discard(val T) {
+	p.mu.Lock()
+	p.live--
+	p.mu.Unlock()
+	p.cond.Signal()
+	p.close([]idleResource[T]{{val: val}})
+}
+
+// EvictIdle closes every resource that has been idle for longer than the
+// pool's ttl. Get does this too, so calling it is only needed to release
+// resources from a pool that has gone quiet.
+func (p *ResourcePool[T]) EvictIdle() {
+	p.mu.Lock()
+	expired := p.takeExpired(time.Now())
+	p.mu.Unlock()
+	if len(expired) > 0 {
+		// Their slots are free now, so waiting Gets may create new ones
+		p.cond.Broadcast()
+	}
+	p.close(expired)
+}
+
+// Stats returns the number of resources that exist and how many of them
+// are sitting idle.
+func (p *ResourcePool[T]) Stats() (live, idle int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.live, len(p.free)
+}
+
+// takeExpired removes expired resources from the free list. It must be
+// called with `mu` held.
+func (p *ResourcePool[T]) takeExpired(now time.Time) []idleResource[T] {
+	if p.ttl <= 0 {
+		return nil
+	}
+	n := 0
+	for n < len(p.free) && now.Sub(p.free[n].since) > p.ttl {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	expired := append([]idleResource[T](nil), p.free[:n]...)
+	p.free = append(p.free[:0], p.free[n:]...)
+	p.live -= n
+	return expired
+}
+
+// close runs closeFn outside the lock, since closing a real connection
+// can be slow.
+func (p *ResourcePool[T]) close(rs []idleResource[T]) {
+	if p.closeFn == nil {
+		return
+	}
+	for _, r := range rs {
+		p.closeFn(r.val)
+	}
+}
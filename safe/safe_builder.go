@@ -0,0 +1,32 @@
+package safe
+
+import (
+	"strings"
+	"sync"
+)
+
+// SafeBuilder is a strings.Builder that many goroutines can write to at
+// once. Each WriteString lands in one piece, but writes from different
+// goroutines end up in whatever order they take the lock.
+type SafeBuilder struct {
+	m sync.Mutex
+	b strings.Builder
+}
+
+func (s *SafeBuilder) WriteString(str string) (int, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.b.WriteString(str)
+}
+
+func (s *SafeBuilder) String() string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.b.String()
+}
+
+func (s *SafeBuilder) Len() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.b.Len()
+}
@@ -0,0 +1,67 @@
+package safe
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SampledLogger keeps a hot loop spread over many goroutines from flooding
+// the logs. Messages are grouped by key, and each group is only passed on
+// to the underlying log function on the first call, then once per every
+// calls and/or once per interval.
+type SampledLogger struct {
+	logFn    func(msg string)
+	every    int64
+	interval time.Duration
+
+	// `keys` maps a message key to its *sampleCounter. sync.Map suits
+	// this well, since keys are written once and then only read
+	keys sync.Map
+}
+
+type sampleCounter struct {
+	calls atomic.Int64
+	// `last` is when this key was last logged, by either rule, in Unix
+	// nanoseconds
+	last atomic.Int64
+}
+
+// NewSampledLogger returns a logger that passes messages to logFn. A key
+// is logged on every every'th call, and also whenever interval has passed
+// since it was last logged. Set either one to zero to turn that kind of
+// sampling off; with both off, every message is logged.
+func NewSampledLogger(logFn func(msg string), every int, interval time.Duration) *SampledLogger {
+	if every < 1 && interval <= 0 {
+		every = 1
+	}
+	return &SampledLogger{logFn: logFn, every: int64(every), interval: interval}
+}
+
+// Log passes msg on to the log function if the sampling for key allows
+// it, and drops it otherwise.
+func (l *SampledLogger) Log(key, msg string) {
+	v, ok := l.keys.Load(key)
+	if !ok {
+		v, _ = l.keys.LoadOrStore(key, &sampleCounter{})
+	}
+	c := v.(*sampleCounter)
+
+	n := c.calls.Add(1)
+	now := time.Now().UnixNano()
+	if l.every > 0 && (n-1)%l.every == 0 {
+		// Restart the interval too, or it would let the very next call
+		// through as well
+		c.last.Store(now)
+		l.logFn(msg)
+		return
+	}
+	if l.interval > 0 {
+		last := c.last.Load()
+		// Many goroutines can see that the interval is up at once; the
+		// CAS makes sure only one of them logs
+		if now-last >= int64(l.interval) && c.last.CompareAndSwap(last, now) {
+			l.logFn(msg)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package safe
+
+import (
+	"sync"
+	"time"
+)
+
+// Scheduler runs functions periodically in the background until Stop is
+// called.
+type Scheduler struct {
+	quit chan struct{}
+	wg   sync.WaitGroup
+
+	// `m` makes Every and Stop safe to call concurrently
+	m       sync.Mutex
+	stopped bool
+}
+
+// NewScheduler returns a Scheduler with nothing scheduled.
+func NewScheduler() *Scheduler {
+	return &Scheduler{quit: make(chan struct{})}
+}
+
+// Every calls fn once per d, starting d from now, on a goroutine of its
+// own. If a call takes longer than d, the ticks missed in the meantime are
+// skipped rather than run back to back. Every does nothing once the
+// Scheduler has been stopped, and panics if d is not positive.
+func (s *Scheduler) Every(d time.Duration, fn func()) {
+	// time.NewTicker would panic over this too, but in the background
+	// goroutine, where the caller can't recover it and it takes the whole
+	// program down
+	if d <= 0 {
+		panic("Scheduler: non-positive interval for Every")
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.stopped {
+		return
+	}
+	s.wg.Add(1)
+	go s.run(d, fn)
+}
+
+// Stop halts every task and waits for calls already in progress to
+// finish, so fn is never called after Stop has returned.
+func (s *Scheduler) Stop() {
+	s.m.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.quit)
+	}
+	s.m.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(d time.Duration, fn func()) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+			// Both cases can be ready at once, and select picks one at
+			// random, so check `quit` again before starting the call
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+			fn()
+			// The ticker keeps one tick waiting while we are busy;
+			// throw it away so a slow call isn't followed right away
+			// by another
+			select {
+			case <-ticker.C:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package safe
+
+import "sync"
+
+// SenderGroup answers the question of who closes a channel that several
+// goroutines send on. Each sending goroutine gets its own Sender, and the
+// channel is closed once every Sender has called Done, so receivers can
+// simply `range` over it.
+//
+// It follows the same rule as a sync.WaitGroup: senders must be added
+// before the ones already added could all have finished, normally by
+// adding each one before starting its goroutine.
+type SenderGroup[T any] struct {
+	ch    chan T
+	wg    sync.WaitGroup
+	start sync.Once
+}
+
+// Sender is the handle one goroutine uses to send on a SenderGroup's
+// channel. It must not be shared between goroutines.
+type Sender[T any] struct {
+	g    *SenderGroup[T]
+	once sync.Once
+}
+
+// NewSenderGroup returns a group whose channel has room for buffer
+// values.
+func NewSenderGroup[T any](buffer int) *SenderGroup[T] {
+	return &SenderGroup[T]{ch: make(chan T, buffer)}
+}
+
+// AddSender registers a new sender and returns its handle.
+func (g *SenderGroup[T]) AddSender() *Sender[T] {
+	g.wg.Add(1)
+	// The first sender starts the goroutine that closes the channel.
+	// It is the only place the channel is ever closed, and it runs only
+	// once every sender is done, so nobody can send on a closed channel
+	g.start.Do(func() {
+		go func() {
+			g.wg.Wait()
+			close(g.ch)
+		}()
+	})
+	return &Sender[T]{g: g}
+}
+
+// Chan returns the channel to receive from.
+func (g *SenderGroup[T]) Chan() <-chan T {
+	return g.ch
+}
+
+// Send sends v on the group's channel. It must not be called after Done.
+func (s *Sender[T]) Send(v T) {
+	s.g.ch <- v
+}
+
+// Done tells the group this sender has finished. Calling it more than
+// once is harmless.
+func (s *Sender[T]) Done() {
+	s.once.Do(s.g.wg.Done)
+}
@@ -0,0 +1,67 @@
+package safe
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// ShardedCounter is a counter for very hot paths. The count is split over
+// several shards, so goroutines incrementing at the same time usually hit
+// different memory instead of all fighting over one atomic.
+//
+// Go does not expose which goroutine or P is running, so Inc picks a
+// shard at random using the runtime's per-thread generator, which needs
+// no shared state. Sum has to visit every shard, which makes it slower
+// than reading a single atomic; this trade only pays off when increments
+// vastly outnumber reads. Run ./benchmarks on a machine with several
+// cores to compare it with a single atomic.
+type ShardedCounter struct {
+	shards []paddedInt64
+	mask   uint32
+}
+
+// paddedInt64 keeps each shard on a cache line of its own, so that two
+// shards never share one and bounce it between CPU cores ("false
+// sharing"). Lines are 64 bytes, which would be enough padding on its
+// own, but some CPUs fetch lines in adjacent pairs, so 128 bytes keeps
+// neighbouring shards out of each other's pair too.
+type paddedInt64 struct {
+	val atomic.Int64
+	_   [120]byte
+}
+
+// NewShardedCounter returns a counter with at least n shards. If n is not
+// positive, one shard per available CPU is used.
+func NewShardedCounter(n int) *ShardedCounter {
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	// Round up to a power of two so a shard can be picked with a mask
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+	return &ShardedCounter{
+		shards: make([]paddedInt64, size),
+		mask:   uint32(size - 1),
+	}
+}
+
+func (c *ShardedCounter) Inc() {
+	c.Add(1)
+}
+
+func (c *ShardedCounter) Add(delta int64) {
+	c.shards[rand.Uint32()&c.mask].val.Add(delta)
+}
+
+// Sum adds up all shards. Increments that happen while Sum is running may
+// or may not be included.
+func (c *ShardedCounter) Sum() int64 {
+	var total int64
+	for i := range c.shards {
+		total += c.shards[i].val.Load()
+	}
+	return total
+}
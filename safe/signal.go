@@ -0,0 +1,104 @@
+package safe
+
+import (
+	"context"
+	"sync"
+)
+
+// Signal passes a single value, set once, to any number of waiting
+// goroutines.
+//
+// `done <- struct{}{}` wakes exactly one receiver: a second one would
+// wait forever. Closing a channel wakes all of them, but a closed channel
+// carries no value. Signal closes a channel so everyone wakes up, and
+// keeps the value next to it for them to read. Readers can also
+// Subscribe, to get the value on a channel of their own.
+//
+// The zero value is not usable; create one with NewSignal.
+type Signal[T any] struct {
+	// Closed by Fire, after `val` is set; reading `val` is only safe once
+	// it has been closed
+	done chan struct{}
+	val  T
+
+	m     sync.Mutex
+	fired bool
+	subs  map[<-chan T]chan T
+}
+
+// NewSignal returns a Signal that has not fired yet.
+func NewSignal[T any]() *Signal[T] {
+	return &Signal[T]{done: make(chan struct{}), subs: make(map[<-chan T]chan T)}
+}
+
+// Fire sets the value and wakes everyone waiting for it. Only the first
+// call has any effect; it reports whether this call was that one.
+func (s *Signal[T]) Fire(v T) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.fired {
+		return false
+	}
+	s.fired = true
+	s.val = v
+	close(s.done)
+	for _, sub := range s.subs {
+		// Every subscriber channel has room for the one value, so this
+		// never blocks, however slow the subscriber is
+		sub <- v
+		close(sub)
+	}
+	s.subs = nil
+	return true
+}
+
+// Done returns a channel that is closed once the Signal has fired, for
+// use in a select. Value can be read after it is closed.
+func (s *Signal[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+// Value returns the value Fire was called with. It must only be called
+// after Done is closed.
+func (s *Signal[T]) Value() T {
+	return s.val
+}
+
+// Wait blocks until the Signal fires and returns its value, or returns
+// ctx.Err() if ctx is done first.
+func (s *Signal[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-s.done:
+		return s.val, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Subscribe returns a channel that receives the value once and is then
+// closed. If the Signal has already fired, the value is waiting in it.
+func (s *Signal[T]) Subscribe() <-chan T {
+	ch := make(chan T, 1)
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.fired {
+		ch <- s.val
+		close(ch)
+		return ch
+	}
+	s.subs[ch] = ch
+	return ch
+}
+
+// Unsubscribe closes ch without sending the value on it, unless the
+// Signal has already fired, in which case ch has its value and is left
+// as it is.
+func (s *Signal[T]) Unsubscribe(ch <-chan T) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if sub, ok := s.subs[ch]; ok {
+		delete(s.subs, ch)
+		close(sub)
+	}
+}
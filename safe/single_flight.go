@@ -0,0 +1,63 @@
+package safe
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// SingleFlight makes concurrent calls for the same key share one call.
+//
+// Lazy runs its function once, ever. SingleFlight only merges the calls
+// that overlap: while fn is running for a key, anyone else asking for that
+// key waits for its result instead of starting another, and once it has
+// returned the next call runs fn again. This is how a cache keeps a burst
+// of misses for one entry from all going to the database.
+type SingleFlight[K comparable, V any] struct {
+	m     sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+type flightCall[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// Do runs fn and returns its result, unless a call for key is already
+// running, in which case it waits for that one and returns its result.
+// shared reports whether the result came from another caller's call. If
+// fn panics, the panic carries on in the goroutine that ran it, and those
+// waiting for it get a *PanicError.
+func (s *SingleFlight[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	s.m.Lock()
+	if call, ok := s.calls[key]; ok {
+		s.m.Unlock()
+		<-call.done
+		return call.val, call.err, true
+	}
+	if s.calls == nil {
+		s.calls = make(map[K]*flightCall[V])
+	}
+	call := &flightCall[V]{done: make(chan struct{})}
+	s.calls[key] = call
+	s.m.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = &PanicError{Value: r, Stack: debug.Stack()}
+			s.finish(key, call)
+			panic(r)
+		}
+		s.finish(key, call)
+	}()
+	call.val, call.err = fn()
+	return call.val, call.err, false
+}
+
+// finish lets waiters read call and makes the next Do for key start afresh
+func (s *SingleFlight[K, V]) finish(key K, call *flightCall[V]) {
+	s.m.Lock()
+	delete(s.calls, key)
+	s.m.Unlock()
+	close(call.done)
+}
@@ -0,0 +1,46 @@
+package safe
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// StateContainer guards a value with a mutex and can be encoded to and
+// decoded from JSON while other goroutines are updating it.
+//
+// The lock is held for the whole of MarshalJSON, so the output is always
+// the value as it was at one point in time, never half of an update.
+// Pass a *StateContainer to json.Marshal so the methods are picked up.
+type StateContainer[T any] struct {
+	m   sync.Mutex
+	val T
+}
+
+func (c *StateContainer[T]) Get() T {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.val
+}
+
+func (c *StateContainer[T]) Set(val T) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.val = val
+}
+
+func (c *StateContainer[T]) MarshalJSON() ([]byte, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return json.Marshal(c.val)
+}
+
+func (c *StateContainer[T]) UnmarshalJSON(data []byte) error {
+	// Decode outside the lock so a bad payload leaves the current value
+	// untouched, and readers are not blocked while we parse
+	var val T
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+	c.Set(val)
+	return nil
+}
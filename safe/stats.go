@@ -0,0 +1,46 @@
+package safe
+
+import "sync/atomic"
+
+// Stats holds a few independent counters that can be bumped from any
+// goroutine without a lock.
+type Stats struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	bytes    atomic.Int64
+}
+
+// StatsSnapshot is a plain copy of the counters in Stats.
+type StatsSnapshot struct {
+	Requests int64
+	Errors   int64
+	Bytes    int64
+}
+
+func (s *Stats) IncRequests() {
+	s.requests.Add(1)
+}
+
+func (s *Stats) IncErrors() {
+	s.errors.Add(1)
+}
+
+func (s *Stats) AddBytes(n int64) {
+	s.bytes.Add(n)
+}
+
+// Load reads every counter and returns them together.
+//
+// Each counter is read atomically, but the counters are read one after the
+// other, so the snapshot as a whole is not atomic: an update that lands
+// between two of the loads is seen by one field and not the other. For
+// example, a snapshot can show an error that has no matching request yet.
+// That is fine for metrics and dashboards; if the fields must agree with
+// each other, guard them all with a single mutex instead.
+func (s *Stats) Load() StatsSnapshot {
+	return StatsSnapshot{
+		Requests: s.requests.Load(),
+		Errors:   s.errors.Load(),
+		Bytes:    s.bytes.Load(),
+	}
+}
@@ -0,0 +1,188 @@
+package safe
+
+import (
+	"context"
+	"sync"
+
+	"github.com/abyanjksatu/race-condition/trace"
+)
+
+// The functions below each set a number to 5 in a new goroutine and read
+// it back, using a different way to avoid a data race between the write
+// and the read. Each one optionally takes a trace.Recorder, which is told
+// about every step so the order they happen in can be printed.
+
+// BlockingWithWaitgroups waits for the write with a sync.WaitGroup.
+func BlockingWithWaitgroups(rec ...*trace.Recorder) int {
+	tr := trace.First(rec)
+	var i int
+	// Initialize a waitgroup variable
+	var wg sync.WaitGroup
+	// `Add(1) signifies that there is 1 task that we need to wait for
+	wg.Add(1)
+	tr.Record("wg.Add(1)")
+	go func() {
+		tr.Record("goroutine started")
+		i = 5
+		tr.Record("write i=5")
+		// Calling `wg.Done` indicates that we are done with the task we are waiting fo
+		tr.Record("wg.Done")
+		wg.Done()
+	}()
+	// `wg.Wait` blocks until `wg.Done` is called the same number of times
+	// as the amount of tasks we have (in this case, 1 time)
+	wg.Wait()
+	tr.Record("wg.Wait returned")
+	tr.Recordf("read i=%d", i)
+	return i
+}
+
+// BlockingWithChannel waits for the write by receiving from a done channel.
+func BlockingWithChannel(rec ...*trace.Recorder) int {
+	tr := trace.First(rec)
+	var i int
+	// Create a channel to push an empty struct to once we're done
+	done := make(chan struct{})
+	go func() {
+		tr.Record("goroutine started")
+		i = 5
+		tr.Record("write i=5")
+		// Push an empty struct once we're done
+		tr.Record("send on done")
+		done <- struct{}{}
+	}()
+	// This statement blocks until something gets pushed into the `done` channel
+	<-done
+	tr.Record("received from done")
+	tr.Recordf("read i=%d", i)
+	return i
+}
+
+// ReturningWithChannel returns at once with a channel the number will be
+// sent on, leaving it to the caller to decide when to block.
+func ReturningWithChannel(rec ...*trace.Recorder) <-chan int {
+	tr := trace.First(rec)
+	// create the channel
+	c := make(chan int)
+	go func() {
+		tr.Record("goroutine started")
+		// push the result into the channel
+		tr.Record("send 5")
+		c <- 5
+	}()
+	// immediately return the channel
+	tr.Record("return channel")
+	return c
+}
+
+// UseMutex guards the number with a SafeValue. It doesn't wait for the
+// write, so it returns either 0 or 5, but never reads a half-written value.
+func UseMutex(rec ...*trace.Recorder) int {
+	tr := trace.First(rec)
+	// Create an instance of `SafeValue`
+	i := &SafeValue[int]{}
+	// Use `Set` and `Get` instead of regular assignments and reads
+	// We can now be sure that we can read only if the write has completed, or vice versa
+	go func() {
+		tr.Record("goroutine started")
+		i.Set(5)
+		tr.Record("Set(5)")
+	}()
+	v := i.Get()
+	tr.Recordf("Get() = %d", v)
+	return v
+}
+
+// UseAtomic is UseMutex with an AtomicNumber in place of the mutex.
+func UseAtomic(rec ...*trace.Recorder) int64 {
+	tr := trace.First(rec)
+	i := &AtomicNumber{}
+	go func() {
+		tr.Record("goroutine started")
+		i.Store(5)
+		tr.Record("Store(5)")
+	}()
+	v := i.Load()
+	tr.Recordf("Load() = %d", v)
+	return v
+}
+
+// The Ctx variants below do the same as the functions above, but give up
+// and return ctx.Err() if the write hasn't happened by the time ctx is
+// done, instead of blocking forever on a goroutine that has stalled.
+
+// BlockingWithWaitgroupsCtx is BlockingWithWaitgroups with a way out.
+func BlockingWithWaitgroupsCtx(ctx context.Context) (int, error) {
+	var i int
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		i = 5
+		wg.Done()
+	}()
+	// `wg.Wait` can't be used in a select, so wait in another goroutine
+	// and have it close a channel when it's done
+	waited := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		return i, nil
+	case <-ctx.Done():
+		// We must not touch `i` here: the write may still be going on
+		return 0, ctx.Err()
+	}
+}
+
+// BlockingWithChannelCtx is BlockingWithChannel with a way out.
+func BlockingWithChannelCtx(ctx context.Context) (int, error) {
+	var i int
+	// Buffered, so the goroutine can still push into it and exit if we
+	// have stopped listening
+	done := make(chan struct{}, 1)
+	go func() {
+		i = 5
+		done <- struct{}{}
+	}()
+	select {
+	case <-done:
+		return i, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// UseMutexCtx is UseMutex with a way out. `Lock` can't be cancelled, so
+// if a writer stalls while holding the lock, the Get is left waiting for
+// it in the background while we return.
+func UseMutexCtx(ctx context.Context) (int, error) {
+	i := &SafeValue[int]{}
+	go func() {
+		i.Set(5)
+	}()
+	return Cancellable(ctx, i.Get)
+}
+
+// BroadcastingWithSignal has readers goroutines all wait for the same
+// write, which a done channel can't do: one send wakes only one of them.
+// It returns what each reader read.
+func BroadcastingWithSignal(readers int) []int {
+	s := NewSignal[int]()
+	read := make([]int, readers)
+	var wg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			// Every reader blocks here until the one write below is done
+			read[r], _ = s.Wait(context.Background())
+		}(r)
+	}
+	go func() {
+		s.Fire(5)
+	}()
+	wg.Wait()
+	return read
+}
@@ -0,0 +1,70 @@
+package safe
+
+import "sync"
+
+// toggleBuffer is how many changes a subscriber can fall behind by
+// before the oldest ones are dropped
+const toggleBuffer = 8
+
+// Toggle is a bool that can be read and written from many goroutines, and
+// tells its subscribers every time it changes.
+type Toggle struct {
+	m    sync.Mutex
+	val  bool
+	subs map[<-chan bool]chan bool
+}
+
+func (t *Toggle) Get() bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return t.val
+}
+
+// Set stores v and, if that changes the value, sends v to every
+// subscriber. Set never waits for a subscriber: if one has fallen
+// toggleBuffer changes behind, its oldest change is dropped to make room,
+// so the last value it receives is always the current one.
+func (t *Toggle) Set(v bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.val == v {
+		return
+	}
+	t.val = v
+	for _, ch := range t.subs {
+		select {
+		case ch <- v:
+		default:
+			// Drop the oldest change. Only Set sends, and it holds the
+			// lock, so afterwards there is always room
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- v
+		}
+	}
+}
+
+// Subscribe returns a channel that receives the new value after each
+// change. Pass it to Unsubscribe when it is no longer needed.
+func (t *Toggle) Subscribe() <-chan bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.subs == nil {
+		t.subs = make(map[<-chan bool]chan bool)
+	}
+	ch := make(chan bool, toggleBuffer)
+	t.subs[ch] = ch
+	return ch
+}
+
+// Unsubscribe stops sending changes to ch and closes it.
+func (t *Toggle) Unsubscribe(ch <-chan bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if sub, ok := t.subs[ch]; ok {
+		delete(t.subs, ch)
+		close(sub)
+	}
+}
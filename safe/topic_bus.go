@@ -0,0 +1,80 @@
+package safe
+
+import "sync"
+
+// topicBuffer is how many messages a subscriber can fall behind by before
+// new ones are dropped for it
+const topicBuffer = 16
+
+// TopicBus delivers published messages to the subscribers of their topic,
+// and to no one else.
+type TopicBus[T any] struct {
+	// Publishing only reads the subscriber maps, so many Publish calls
+	// can share the read lock; Subscribe and Unsubscribe take the write
+	// lock to change them
+	m      sync.RWMutex
+	topics map[string]map[<-chan T]chan T
+}
+
+// NewTopicBus returns a bus with no subscribers.
+func NewTopicBus[T any]() *TopicBus[T] {
+	return &TopicBus[T]{topics: make(map[string]map[<-chan T]chan T)}
+}
+
+// Subscribe returns a channel that receives every message published to
+// topic from now on. Pass it to Unsubscribe when it is no longer needed.
+func (b *TopicBus[T]) Subscribe(topic string) <-chan T {
+	b.m.Lock()
+	defer b.m.Unlock()
+	subs, ok := b.topics[topic]
+	if !ok {
+		subs = make(map[<-chan T]chan T)
+		b.topics[topic] = subs
+	}
+	ch := make(chan T, topicBuffer)
+	subs[ch] = ch
+	return ch
+}
+
+// Unsubscribe stops delivering topic to ch and closes it. A topic with no
+// subscribers left is removed.
+func (b *TopicBus[T]) Unsubscribe(topic string, ch <-chan T) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	subs := b.topics[topic]
+	sub, ok := subs[ch]
+	if !ok {
+		return
+	}
+	// Holding the write lock means no Publish is sending right now, so
+	// closing the channel can't cause a send on a closed channel
+	close(sub)
+	delete(subs, ch)
+	if len(subs) == 0 {
+		delete(b.topics, topic)
+	}
+}
+
+// Publish sends v to every subscriber of topic and returns how many got
+// it. Publish never blocks: a subscriber whose buffer is full misses the
+// message.
+func (b *TopicBus[T]) Publish(topic string, v T) int {
+	b.m.RLock()
+	defer b.m.RUnlock()
+	delivered := 0
+	for _, ch := range b.topics[topic] {
+		select {
+		case ch <- v:
+			delivered++
+		default:
+		}
+	}
+	return delivered
+}
+
+// Topics returns the number of topics with at least one subscriber.
+func (b *TopicBus[T]) Topics() int {
+	b.m.RLock()
+	defer b.m.RUnlock()
+	return len(b.topics)
+}
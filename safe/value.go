@@ -0,0 +1,51 @@
+package safe
+
+import "sync"
+
+// SafeValue holds a value of any type behind a mutex, so it can be read
+// and written from many goroutines. The zero value is ready to use and
+// holds the zero value of T.
+type SafeValue[T any] struct {
+	val T
+	m   sync.Mutex
+}
+
+func (v *SafeValue[T]) Get() T {
+	// The `Lock` method of the mutex blocks if it is already locked
+	// if not, then it blocks other calls until the `Unlock` method is called
+	v.m.Lock()
+	// Defer `Unlock` until this method returns
+	defer v.m.Unlock()
+	// Return the value
+	return v.val
+}
+
+func (v *SafeValue[T]) Set(val T) {
+	// Similar to the `Get` method, except we Lock until we are done
+	// writing to `v.val`
+	v.m.Lock()
+	defer v.m.Unlock()
+	v.val = val
+}
+
+// Swap stores val and returns the value it replaced.
+func (v *SafeValue[T]) Swap(val T) T {
+	v.m.Lock()
+	defer v.m.Unlock()
+	old := v.val
+	v.val = val
+	return old
+}
+
+// Update replaces the value with fn(current value) and returns the result.
+//
+// A Get followed by a Set is not enough for a read-modify-write such as
+// an increment: another goroutine can Set in between, and its write is
+// lost. Update holds the lock for the whole step, so fn must be quick
+// and must not call back into v.
+func (v *SafeValue[T]) Update(fn func(T) T) T {
+	v.m.Lock()
+	defer v.m.Unlock()
+	v.val = fn(v.val)
+	return v.val
+}
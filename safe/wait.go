@@ -0,0 +1,36 @@
+package safe
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBadInterval is returned by WaitForCondition for an interval that is
+// not positive.
+var ErrBadInterval = errors.New("non-positive polling interval")
+
+// WaitForCondition calls cond right away and then once every interval until
+// it returns true, in which case it returns nil, or until ctx is done, in
+// which case it returns ctx.Err(). It returns ErrBadInterval, without
+// calling cond, if interval is not positive.
+func WaitForCondition(ctx context.Context, interval time.Duration, cond func() bool) error {
+	if interval <= 0 {
+		return ErrBadInterval
+	}
+	if cond() {
+		return nil
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if cond() {
+				return nil
+			}
+		}
+	}
+}
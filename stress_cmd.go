@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"runtime/metrics"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abyanjksatu/race-condition/safe"
+)
+
+// stressTarget is one of the safe types under load. Only the map uses
+// the key; the others hold a single number.
+type stressTarget interface {
+	read(key int)
+	write(key int)
+	close()
+}
+
+type mutexTarget struct{ v safe.SafeValue[int] }
+
+func (t *mutexTarget) read(int) { t.v.Get() }
+func (t *mutexTarget) write(int) {
+	t.v.Update(func(n int) int { return n + 1 })
+}
+func (t *mutexTarget) close() {}
+
+type rwTarget struct{ v safe.SafeNumberRW }
+
+func (t *rwTarget) read(int)  { t.v.Get() }
+func (t *rwTarget) write(int) { t.v.Inc() }
+func (t *rwTarget) close()    {}
+
+type atomicTarget struct{ v safe.AtomicNumber }
+
+func (t *atomicTarget) read(int)  { t.v.Load() }
+func (t *atomicTarget) write(int) { t.v.Add(1) }
+func (t *atomicTarget) close()    {}
+
+// channelTarget keeps the number in a server goroutine; a request of 0
+// reads it and 1 adds one to it
+type channelTarget struct {
+	rc     *safe.RequestChannel[int, int]
+	cancel context.CancelFunc
+}
+
+func newChannelTarget() *channelTarget {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &channelTarget{rc: safe.NewRequestChannel[int, int](), cancel: cancel}
+	var n int
+	go t.rc.Serve(ctx, func(delta int) int {
+		n += delta
+		return n
+	})
+	return t
+}
+
+func (t *channelTarget) read(int)  { t.rc.Do(context.Background(), 0) }
+func (t *channelTarget) write(int) { t.rc.Do(context.Background(), 1) }
+func (t *channelTarget) close()    { t.cancel() }
+
+type mapTarget struct{ m *safe.SafeMap[int, int] }
+
+func (t *mapTarget) read(key int)  { t.m.Get(key) }
+func (t *mapTarget) write(key int) { t.m.Set(key, key) }
+func (t *mapTarget) close()        {}
+
+type stressType struct {
+	name string
+	make func() stressTarget
+}
+
+var stressTypes = []stressType{
+	{"mutex", func() stressTarget { return &mutexTarget{} }},
+	{"rwmutex", func() stressTarget { return &rwTarget{} }},
+	{"atomic", func() stressTarget { return &atomicTarget{} }},
+	{"channel", func() stressTarget { return newChannelTarget() }},
+	{"map", func() stressTarget { return &mapTarget{m: safe.NewSafeMap[int, int](0)} }},
+}
+
+// Timing every operation would cost about as much as an atomic add, so
+// only one in this many is timed
+const stressSampleEvery = 16
+
+func init() {
+	names := make([]string, len(stressTypes))
+	for i, t := range stressTypes {
+		names[i] = t.name
+	}
+	commands["stress"] = command{
+		usage: "stress [" + strings.Join(names, "|") + "|all] [-goroutines N] [-reads P] [-duration D] [-keys N]",
+		run:   stressCmd,
+	}
+}
+
+// stressCmd puts each chosen type under a mix of reads and writes for a
+// while, then prints the throughput, latency and time spent waiting on
+// mutexes, so the types can be compared on the same workload
+func stressCmd(args []string) error {
+	fs := newFlagSet("stress")
+	goroutines := fs.Int("goroutines", 8, "number of goroutines using the type at once")
+	reads := fs.Int("reads", 90, "percentage of operations that are reads")
+	duration := fs.Duration("duration", time.Second, "how long to run each type for")
+	keys := fs.Int("keys", 1024, "number of distinct keys used with the map")
+	name, err := parseWithArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = "all"
+	}
+	if *goroutines < 1 || *keys < 1 {
+		return errors.New("stress: -goroutines and -keys must be at least 1")
+	}
+	if *reads < 0 || *reads > 100 {
+		return errors.New("stress: -reads must be between 0 and 100")
+	}
+	if *duration <= 0 {
+		return errors.New("stress: -duration must be positive")
+	}
+	if name != "all" && !slices.ContainsFunc(stressTypes, func(t stressType) bool { return t.name == name }) {
+		return fmt.Errorf("stress: unknown type %q", name)
+	}
+
+	fmt.Printf("%d goroutines, %d%% reads, %v per type\n\n", *goroutines, *reads, *duration)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "type\tops/sec\tp50\tp99\tmutex wait\t")
+	for _, t := range stressTypes {
+		if name != "all" && name != t.name {
+			continue
+		}
+		target := t.make()
+		r := stress(target, *goroutines, *reads, *keys, *duration)
+		target.close()
+		fmt.Fprintf(w, "%s\t%.0f\t%v\t%v\t%v\t\n", t.name, r.opsPerSec, r.p50, r.p99, r.mutexWait.Round(time.Microsecond))
+	}
+	return w.Flush()
+}
+
+type stressResult struct {
+	opsPerSec float64
+	p50, p99  time.Duration
+	// Total time goroutines spent blocked on a sync.Mutex or RWMutex,
+	// across all of them, so it can be more than the run took
+	mutexWait time.Duration
+}
+
+// stress runs reads and writes against target from goroutines goroutines
+// until d has passed
+func stress(target stressTarget, goroutines, readPercent, keys int, d time.Duration) stressResult {
+	var (
+		stop    atomic.Bool
+		ops     atomic.Int64
+		m       sync.Mutex
+		samples []time.Duration
+		wg      sync.WaitGroup
+	)
+	waitBefore := mutexWait()
+	start := time.Now()
+	time.AfterFunc(d, func() { stop.Store(true) })
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Like runStrategy, keep everything local until the end so
+			// the bookkeeping doesn't add contention of its own
+			var local []time.Duration
+			n := 0
+			for !stop.Load() {
+				key := rand.IntN(keys)
+				isRead := rand.IntN(100) < readPercent
+				timed := n%stressSampleEvery == 0
+				var t0 time.Time
+				if timed {
+					t0 = time.Now()
+				}
+				if isRead {
+					target.read(key)
+				} else {
+					target.write(key)
+				}
+				if timed {
+					local = append(local, time.Since(t0))
+				}
+				n++
+			}
+			ops.Add(int64(n))
+			m.Lock()
+			samples = append(samples, local...)
+			m.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	slices.Sort(samples)
+	return stressResult{
+		opsPerSec: float64(ops.Load()) / elapsed.Seconds(),
+		p50:       percentile(samples, 50),
+		p99:       percentile(samples, 99),
+		mutexWait: mutexWait() - waitBefore,
+	}
+}
+
+// percentile returns the p-th percentile of sorted
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+// mutexWait returns how long goroutines have spent waiting for mutexes
+// since the program started, as counted by the runtime
+func mutexWait() time.Duration {
+	s := []metrics.Sample{{Name: "/sync/mutex/wait/total:seconds"}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return time.Duration(s[0].Value.Float64() * float64(time.Second))
+}
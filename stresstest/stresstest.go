@@ -0,0 +1,64 @@
+// Package stresstest helps check that a type is safe for concurrent use.
+//
+// The helpers hammer a type from many goroutines at once. They catch
+// panics and impossible values themselves, but the real work of spotting
+// unsynchronized access is done by the race detector, so tests using
+// them should run with `go test -race`.
+package stresstest
+
+import (
+	"sync"
+	"testing"
+)
+
+// StressReadWrite starts goroutines writers and as many readers, each
+// making iterations calls to set or get, all at the same time. It fails t
+// if any call panics or if get returns a value that no writer ever set.
+//
+// Writers only set values in [0, goroutines*iterations), so the value
+// being accessed must start out in that range too (the zero value of an
+// int-backed type does).
+func StressReadWrite(t testing.TB, get func() int, set func(int), goroutines, iterations int) {
+	t.Helper()
+	if !raceEnabled {
+		t.Log("stresstest: race detector is off, only panics and impossible values will be caught")
+	}
+	limit := goroutines * iterations
+
+	var wg sync.WaitGroup
+	// All goroutines wait on `start` so they really do run at the same
+	// time, instead of the first ones finishing before the last start
+	start := make(chan struct{})
+	for g := 0; g < goroutines; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			defer recoverTo(t, "set")
+			<-start
+			for i := 0; i < iterations; i++ {
+				set(g*iterations + i)
+			}
+		}(g)
+		go func() {
+			defer wg.Done()
+			defer recoverTo(t, "get")
+			<-start
+			for i := 0; i < iterations; i++ {
+				if v := get(); v < 0 || v >= limit {
+					t.Errorf("stresstest: get returned %d, which was never set", v)
+					return
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+}
+
+// recoverTo turns a panic in one of the stress goroutines into a test
+// failure. A panic there would otherwise crash the whole test binary.
+func recoverTo(t testing.TB, op string) {
+	if r := recover(); r != nil {
+		t.Errorf("stresstest: %s panicked: %v", op, r)
+	}
+}
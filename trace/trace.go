@@ -0,0 +1,148 @@
+// Package trace records what goroutines do, and in what order, so the
+// interleaving of a concurrent program can be printed and studied.
+//
+// Recording takes a lock, and a lock is itself synchronization: it adds
+// happens-before edges between the goroutines being traced. That can
+// hide a data race from the race detector, so don't trace code that is
+// being checked with -race.
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is one thing that happened, on one goroutine.
+type Event struct {
+	// At is the time since the Recorder was created
+	At        time.Duration `json:"at_ns"`
+	Goroutine uint64        `json:"goroutine"`
+	Msg       string        `json:"msg"`
+}
+
+// Recorder collects Events from any number of goroutines. A nil
+// *Recorder is valid and records nothing, so code can be traced
+// optionally without checking first.
+type Recorder struct {
+	start  time.Time
+	m      sync.Mutex
+	events []Event
+}
+
+// NewRecorder returns an empty Recorder whose clock starts now.
+func NewRecorder() *Recorder {
+	return &Recorder{start: time.Now()}
+}
+
+// First returns the first recorder in recs, or nil if there is none. It
+// lets a function take a recorder as an optional trailing argument.
+func First(recs []*Recorder) *Recorder {
+	if len(recs) == 0 {
+		return nil
+	}
+	return recs[0]
+}
+
+// Record adds an event for the calling goroutine.
+func (r *Recorder) Record(msg string) {
+	if r == nil {
+		return
+	}
+	e := Event{At: time.Since(r.start), Goroutine: goroutineID(), Msg: msg}
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.events = append(r.events, e)
+}
+
+// Recordf is Record with fmt.Sprintf formatting. The message is only
+// formatted if r is not nil.
+func (r *Recorder) Recordf(format string, args ...any) {
+	if r == nil {
+		return
+	}
+	r.Record(fmt.Sprintf(format, args...))
+}
+
+// Events returns a copy of the events recorded so far, in time order.
+func (r *Recorder) Events() []Event {
+	if r == nil {
+		return nil
+	}
+	r.m.Lock()
+	events := append([]Event(nil), r.events...)
+	r.m.Unlock()
+	// Events are appended in the order they get the lock, which can
+	// differ slightly from the order they happened in
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At < events[j].At })
+	return events
+}
+
+// WriteJSON writes the events as a JSON array.
+func (r *Recorder) WriteJSON(w io.Writer) error {
+	events := r.Events()
+	if events == nil {
+		events = []Event{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(events)
+}
+
+// WriteTimeline writes the events as an ASCII timeline: one row per event
+// and one column per goroutine, so that reading down the page shows the
+// order things happened in and reading across shows who did them.
+func (r *Recorder) WriteTimeline(w io.Writer) error {
+	events := r.Events()
+	// Columns go in the order goroutines first show up
+	var ids []uint64
+	col := make(map[uint64]int)
+	width := 0
+	for _, e := range events {
+		if _, ok := col[e.Goroutine]; !ok {
+			col[e.Goroutine] = len(ids)
+			ids = append(ids, e.Goroutine)
+		}
+		if len(e.Msg) > width {
+			width = len(e.Msg)
+		}
+	}
+	width += 2
+
+	var header strings.Builder
+	fmt.Fprintf(&header, "%10s  ", "time")
+	for _, id := range ids {
+		fmt.Fprintf(&header, "%-*s", width, "g"+strconv.FormatUint(id, 10))
+	}
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(header.String(), " "))
+	b.WriteString("\n")
+	for _, e := range events {
+		fmt.Fprintf(&b, "%10s  %s%s\n", e.At.Round(time.Microsecond),
+			strings.Repeat(" ", col[e.Goroutine]*width), e.Msg)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// goroutineID returns the runtime's ID for the calling goroutine. Go
+// deliberately doesn't expose it, so it is parsed from the first line of
+// the stack trace, "goroutine 7 [running]:". That is fine for tracing,
+// and nothing else should rely on it.
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	line := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	if i := bytes.IndexByte(line, ' '); i > 0 {
+		line = line[:i]
+	}
+	id, _ := strconv.ParseUint(string(line), 10, 64)
+	return id
+}